@@ -3,6 +3,7 @@ module github.com/angelchiav/blog-aggregator-go
 go 1.25
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
 )
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/angelchiav/blog-aggregator-go/internal/config"
+	"github.com/angelchiav/blog-aggregator-go/internal/database"
+)
+
+var errDBDown = errors.New("connection refused")
+
+// failingDB is a database.DBTX on which every query fails.
+type failingDB struct{}
+
+func (failingDB) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, errDBDown
+}
+
+func (failingDB) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errDBDown
+}
+
+func (failingDB) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errDBDown
+}
+
+func (failingDB) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return &sql.Row{}
+}
+
+func TestAggOnceReportsClaimErrors(t *testing.T) {
+	s := &State{Cfg: &config.Config{}, DB: database.New(failingDB{})}
+
+	err := aggOnce(context.Background(), s, 1)
+	if !errors.Is(err, errDBDown) {
+		t.Fatalf("aggOnce() = %v, want %v", err, errDBDown)
+	}
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -17,8 +18,10 @@ import (
 )
 
 type State struct {
-	Cfg *config.Config
-	DB  *database.Queries
+	Cfg     *config.Config
+	DB      *database.Queries
+	Conn    *sql.DB
+	Fetcher *rss.Fetcher
 }
 
 type Command struct {
@@ -27,32 +30,42 @@ type Command struct {
 }
 
 type Commands struct {
-	Handlers map[string]func(*State, Command) error
+	Handlers map[string]func(context.Context, *State, Command) error
 }
 
-func (c *Commands) Register(name string, f func(*State, Command) error) {
+func (c *Commands) Register(name string, f func(context.Context, *State, Command) error) {
 	if c.Handlers == nil {
-		c.Handlers = make(map[string]func(*State, Command) error)
+		c.Handlers = make(map[string]func(context.Context, *State, Command) error)
 	}
 	c.Handlers[name] = f
 }
 
-func (c *Commands) Run(s *State, cmd Command) error {
+// Method adapts a State method expression such as (*State).HandlerLogin,
+// which takes the receiver first, to the handler signature.
+func Method(handler func(*State, context.Context, Command) error) func(context.Context, *State, Command) error {
+	return func(ctx context.Context, s *State, cmd Command) error {
+		return handler(s, ctx, cmd)
+	}
+}
+
+// Run dispatches cmd to its handler. ctx is cancelled when the process is
+// asked to stop, so long-running handlers such as agg can shut down cleanly.
+func (c *Commands) Run(ctx context.Context, s *State, cmd Command) error {
 	h, ok := c.Handlers[cmd.Name]
 	if !ok {
 		return fmt.Errorf("unknown command: %s", cmd.Name)
 	}
-	return h(s, cmd)
+	return h(ctx, s, cmd)
 }
 
-func (s *State) HandlerLogin(cmd Command) error {
+func (s *State) HandlerLogin(ctx context.Context, cmd Command) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("a username is required")
 	}
 	username := strings.TrimSpace(cmd.Args[0])
 
 	// Verify user exists in DB
-	if _, err := s.DB.GetUserByName(context.Background(), username); err != nil {
+	if _, err := s.DB.GetUserByName(ctx, username); err != nil {
 		return fmt.Errorf("no such user")
 	}
 
@@ -64,14 +77,14 @@ func (s *State) HandlerLogin(cmd Command) error {
 	return nil
 }
 
-func (s *State) HandlerRegister(cmd Command) error {
+func (s *State) HandlerRegister(ctx context.Context, cmd Command) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("missing <name>")
 	}
 	name := strings.TrimSpace(cmd.Args[0])
 	now := time.Now()
 
-	user, err := s.DB.CreateUser(context.Background(), database.CreateUserParams{
+	user, err := s.DB.CreateUser(ctx, database.CreateUserParams{
 		ID:        uuid.New(),
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -95,16 +108,16 @@ func (s *State) HandlerRegister(cmd Command) error {
 	return nil
 }
 
-func (s *State) HandlerReset(cmd Command) error {
-	if err := s.DB.Reset(context.Background()); err != nil {
+func (s *State) HandlerReset(ctx context.Context, cmd Command) error {
+	if err := s.DB.Reset(ctx); err != nil {
 		return err
 	}
 	fmt.Println("All users deleted.")
 	return nil
 }
 
-func (s *State) HandlerUsers(cmd Command) error {
-	users, err := s.DB.GetUsers(context.Background())
+func (s *State) HandlerUsers(ctx context.Context, cmd Command) error {
+	users, err := s.DB.GetUsers(ctx)
 	if err != nil {
 		return fmt.Errorf("error fetching users: %v", err)
 	}
@@ -126,9 +139,22 @@ func (s *State) HandlerUsers(cmd Command) error {
 	return nil
 }
 
-func (s *State) HandlerAgg(cmd Command) error {
+func (s *State) HandlerAgg(ctx context.Context, cmd Command) error {
 	if len(cmd.Args) < 1 {
-		return fmt.Errorf("usage: agg <time_between_reqs>")
+		return fmt.Errorf("usage: agg <time_between_reqs|--once> [concurrency]")
+	}
+
+	concurrency := 1
+	if len(cmd.Args) >= 2 {
+		n, err := strconv.Atoi(cmd.Args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid concurrency %q: must be a positive integer", cmd.Args[1])
+		}
+		concurrency = n
+	}
+
+	if cmd.Args[0] == "--once" {
+		return aggOnce(ctx, s, concurrency)
 	}
 
 	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
@@ -136,20 +162,103 @@ func (s *State) HandlerAgg(cmd Command) error {
 		return fmt.Errorf("invalid duration %q: %w", cmd.Args[0], err)
 	}
 
-	fmt.Printf("Collecting feeds every %s\n", timeBetweenRequests)
+	fmt.Printf("Collecting up to %d feeds every %s\n", concurrency, timeBetweenRequests)
 
 	ticker := time.NewTicker(timeBetweenRequests)
 	defer ticker.Stop()
 
+	var total scrapeSummary
 	for {
-		if err := scrapeFeeds(s); err != nil {
+		summary, err := scrapeFeeds(ctx, s, concurrency, time.Now())
+		total.add(summary)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error scraping feeds: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Stopping: fetched %d feeds (%d new posts), %d failed, %d aborted\n",
+				total.Fetched, total.NewPosts, total.Failed, total.Aborted)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// aggOnce scrapes every due feed a single time, for use from cron. It
+// fails if any feed could not be fetched or the feeds could not be claimed.
+func aggOnce(ctx context.Context, s *State, concurrency int) error {
+	started := time.Now()
+
+	var (
+		total   scrapeSummary
+		lastErr error
+	)
+	for ctx.Err() == nil {
+		summary, err := scrapeFeeds(ctx, s, concurrency, started)
+		total.add(summary)
+		if err != nil && ctx.Err() == nil {
 			log.Printf("error scraping feeds: %v\n", err)
+			lastErr = err
 		}
-		<-ticker.C
+		if summary.Claimed == 0 {
+			break
+		}
+	}
+
+	fmt.Printf("Fetched %d feeds (%d new posts), %d failed, %d aborted\n",
+		total.Fetched, total.NewPosts, total.Failed, total.Aborted)
+
+	if total.Failed > 0 {
+		return errors.Join(fmt.Errorf("%d feeds failed to fetch", total.Failed), lastErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("interrupted: %w", err)
 	}
+	return lastErr
 }
 
-func HandlerAddFeed(s *State, cmd Command, user database.User) error {
+func (s *State) HandlerFetch(ctx context.Context, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: fetch <url>")
+	}
+
+	feed, err := s.DB.GetFeedByURL(ctx, strings.TrimSpace(cmd.Args[0]))
+	if err != nil {
+		return fmt.Errorf("feed url does not exist: %v", err)
+	}
+	if feed.Status != feedStatusActive {
+		return fmt.Errorf("feed is %s, run enable-feed %s first", feed.Status, feed.Url)
+	}
+
+	// Take the same lease agg does, so a running aggregator and this
+	// command never fetch the feed at the same time.
+	feed, err = s.DB.ClaimFeed(ctx, database.ClaimFeedParams{
+		LeaseSeconds: feedLease.Seconds(),
+		ID:           feed.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("feed is being fetched by an aggregator, try again later")
+	}
+	if err != nil {
+		return fmt.Errorf("could not claim feed: %w", err)
+	}
+	defer releaseFeedLease(context.WithoutCancel(ctx), s, feed)
+
+	report, err := processFeed(ctx, s, feed)
+	if err != nil {
+		return err
+	}
+
+	if report.NotModified {
+		return nil
+	}
+
+	fmt.Printf("%d new posts, %d updated, %d already stored\n", report.NewPosts, report.Updated, report.Existing)
+	return nil
+}
+
+func HandlerAddFeed(ctx context.Context, s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) < 2 {
 		return fmt.Errorf("usage: addfeed <name> <url>")
 	}
@@ -161,13 +270,11 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 		return fmt.Errorf("invalid url: %v", err)
 	}
 
-	feed, err := s.addFeed(user, name, feedURL)
+	feed, err := s.addFeed(ctx, user, name, feedURL)
 	if err != nil {
 		return err
 	}
 
-	ctx := context.Background()
-
 	_, err = s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
@@ -184,27 +291,173 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-func (s *State) HandlerGetFeed(cmd Command) error {
+func (s *State) HandlerGetFeed(ctx context.Context, cmd Command) error {
+	asJSON := len(cmd.Args) > 0 && cmd.Args[0] == "--json"
 
-	feed, err := s.DB.GetFeed(context.Background())
+	feed, err := s.DB.GetFeed(ctx)
 	if err != nil {
 		return fmt.Errorf("no feed found: %v", err)
 	}
 
+	feeds := make([]feedJSON, 0, len(feed))
 	for _, f := range feed {
 
-		id, err := s.DB.GetUserNameById(context.Background(), f.UserID)
+		id, err := s.DB.GetUserNameById(ctx, f.UserID)
 		if err != nil {
 			return fmt.Errorf("no user with this id: %v", err)
 		}
 
+		if asJSON {
+			feeds = append(feeds, newFeedJSON(f, strings.Join(id, ", ")))
+			continue
+		}
+
+		if f.Status != feedStatusActive {
+			fmt.Printf("%s (%s) - (%s) [%s]\n", f.Name, f.Url, id, f.Status)
+			continue
+		}
 		fmt.Printf("%s (%s) - (%s)\n", f.Name, f.Url, id)
 	}
+	if asJSON {
+		return writeJSON(feeds)
+	}
+	return nil
+}
+
+func (s *State) HandlerBrokenFeeds(ctx context.Context, cmd Command) error {
+	feeds, err := s.DB.GetBrokenFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get broken feeds: %w", err)
+	}
+
+	if len(feeds) == 0 {
+		fmt.Println("No broken feeds.")
+		return nil
+	}
+
+	for _, f := range feeds {
+		state := "failing"
+		if f.Status != feedStatusActive {
+			state = f.Status
+		}
+		if f.FailingSince.Valid {
+			state += " since " + f.FailingSince.Time.Format(time.RFC1123)
+		}
+
+		fmt.Printf("%s (%s) - %s, %d consecutive failures\n", f.Name, f.Url, state, f.ConsecutiveFailures)
+		if f.LastError.Valid {
+			fmt.Printf("  last error at %s: %s\n", f.LastErrorAt.Time.Format(time.RFC1123), f.LastError.String)
+		}
+	}
+	return nil
+}
+
+func (s *State) HandlerEnableFeed(ctx context.Context, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: enable-feed <url>")
+	}
+
+	feedURL := strings.TrimSpace(cmd.Args[0])
+
+	n, err := s.DB.EnableFeed(ctx, feedURL)
+	if err != nil {
+		return fmt.Errorf("could not enable feed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("feed url does not exist: %s", feedURL)
+	}
+
+	fmt.Printf("feed enabled: %s\n", feedURL)
 	return nil
 }
 
-func (s *State) addFeed(user database.User, name string, url string) (database.Feed, error) {
-	feed, err := s.DB.CreateFeed(context.Background(), database.CreateFeedParams{
+func (s *State) HandlerFeedLog(ctx context.Context, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: feed-log <url> [limit]")
+	}
+
+	limit := 10
+	if len(cmd.Args) >= 2 {
+		n, err := strconv.Atoi(cmd.Args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid limit %q: must be a positive integer", cmd.Args[1])
+		}
+		limit = n
+	}
+
+	feed, err := s.DB.GetFeedByURL(ctx, strings.TrimSpace(cmd.Args[0]))
+	if err != nil {
+		return fmt.Errorf("feed url does not exist: %v", err)
+	}
+
+	fetches, err := s.DB.GetFeedFetches(ctx, database.GetFeedFetchesParams{
+		FeedID: feed.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("could not get fetch log: %w", err)
+	}
+
+	if len(fetches) == 0 {
+		fmt.Println("No fetches recorded.")
+		return nil
+	}
+
+	for _, f := range fetches {
+		status := "---"
+		if f.StatusCode.Valid {
+			status = strconv.Itoa(int(f.StatusCode.Int32))
+		}
+
+		duration := time.Duration(f.DurationMs) * time.Millisecond
+		fmt.Printf("%s  %s  %8s  %d new", f.StartedAt.Format(time.RFC3339), status, duration, f.NewPosts)
+		if f.Error.Valid {
+			fmt.Printf("  error: %s", f.Error.String)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func (s *State) HandlerFeedInfo(ctx context.Context, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: feed-info <url>")
+	}
+
+	feed, err := s.DB.GetFeedByURL(ctx, strings.TrimSpace(cmd.Args[0]))
+	if err != nil {
+		return fmt.Errorf("feed url does not exist: %v", err)
+	}
+
+	fmt.Printf("Name: %s\nURL: %s\nStatus: %s\n", feed.Name, feed.Url, feed.Status)
+
+	fields := []struct {
+		label string
+		value sql.NullString
+	}{
+		{"Site", feed.SiteUrl},
+		{"Description", feed.Description},
+		{"Language", feed.Language},
+		{"Generator", feed.Generator},
+		{"Icon", feed.IconUrl},
+		{"Image", feed.ImageUrl},
+	}
+	for _, f := range fields {
+		if f.value.Valid {
+			fmt.Printf("%s: %s\n", f.label, f.value.String)
+		}
+	}
+
+	if feed.LastFetchedAt.Valid {
+		fmt.Printf("Last fetched: %s\n", feed.LastFetchedAt.Time.Format(time.RFC1123))
+	} else {
+		fmt.Println("Last fetched: never (metadata is filled in by the first fetch)")
+	}
+	return nil
+}
+
+func (s *State) addFeed(ctx context.Context, user database.User, name string, url string) (database.Feed, error) {
+	feed, err := s.DB.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -219,13 +472,13 @@ func (s *State) addFeed(user database.User, name string, url string) (database.F
 	return feed, nil
 }
 
-func feedFollow(s *State, user database.User, url string) (database.CreateFeedFollowRow, error) {
-	feed, err := s.DB.GetFeedByURL(context.Background(), url)
+func feedFollow(ctx context.Context, s *State, user database.User, url string) (database.CreateFeedFollowRow, error) {
+	feed, err := s.DB.GetFeedByURL(ctx, url)
 	if err != nil {
 		return database.CreateFeedFollowRow{}, fmt.Errorf("feed url does not exist: %v", err)
 	}
 
-	feedFollow, err := s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	feedFollow, err := s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -239,14 +492,14 @@ func feedFollow(s *State, user database.User, url string) (database.CreateFeedFo
 	return feedFollow, nil
 }
 
-func HandlerFeedFollow(s *State, cmd Command, user database.User) error {
+func HandlerFeedFollow(ctx context.Context, s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("usage: follow <url>")
 	}
 
 	url := cmd.Args[0]
 
-	feedfollow, err := feedFollow(s, user, url)
+	feedfollow, err := feedFollow(ctx, s, user, url)
 	if err != nil {
 		return err
 	}
@@ -256,9 +509,7 @@ func HandlerFeedFollow(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-func HandlerFeedFollowing(s *State, cmd Command, user database.User) error {
-	ctx := context.Background()
-
+func HandlerFeedFollowing(ctx context.Context, s *State, cmd Command, user database.User) error {
 	rows, err := s.DB.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
 		return fmt.Errorf("could not fetch follows: %v", err)
@@ -270,15 +521,17 @@ func HandlerFeedFollowing(s *State, cmd Command, user database.User) error {
 	}
 
 	for _, row := range rows {
+		if row.FeedStatus != feedStatusActive {
+			fmt.Printf("- %s (%s) [%s]\n", row.FeedName, row.UserName, row.FeedStatus)
+			continue
+		}
 		fmt.Printf("- %s (%s)\n", row.FeedName, row.UserName)
 	}
 
 	return nil
 }
 
-func feedUnfollow(s *State, user database.User, url string) error {
-	ctx := context.Background()
-
+func feedUnfollow(ctx context.Context, s *State, user database.User, url string) error {
 	feed, err := s.DB.GetFeedByURL(ctx, url)
 	if err != nil {
 		return fmt.Errorf("feed url does not exist: %v", err)
@@ -295,14 +548,14 @@ func feedUnfollow(s *State, user database.User, url string) error {
 	return nil
 }
 
-func HandlerFeedUnfollow(s *State, cmd Command, user database.User) error {
+func HandlerFeedUnfollow(ctx context.Context, s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) < 1 {
 		return fmt.Errorf("usage: unfollow <url>")
 	}
 
 	url := cmd.Args[0]
 
-	if err := feedUnfollow(s, user, url); err != nil {
+	if err := feedUnfollow(ctx, s, user, url); err != nil {
 		return err
 	}
 
@@ -311,117 +564,187 @@ func HandlerFeedUnfollow(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-func scrapeFeeds(s *State) error {
-	ctx := context.Background()
+func HandlerBrowse(ctx context.Context, s *State, cmd Command, user database.User) error {
+	limit := 2
+	full := false
+	asJSON := false
+	var category sql.NullString
+
+	for i := 0; i < len(cmd.Args); i++ {
+		switch arg := cmd.Args[i]; arg {
+		case "--full":
+			full = true
+		case "--json":
+			asJSON = true
+		case "--category":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("usage: browse [limit] [--category <name>] [--full] [--json]")
+			}
+			i++
+			category = sql.NullString{String: cmd.Args[i], Valid: true}
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid limit %q", arg)
+			}
+			limit = n
+		}
+	}
 
-	feed, err := s.DB.GetNextFeedToFetch(ctx)
+	rows, err := s.DB.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID:   user.ID,
+		Category: category,
+		MaxPosts: int32(limit),
+	})
 	if err != nil {
-		return fmt.Errorf("could not get next feed to fetch: %w", err)
+		return fmt.Errorf("could not get posts: %w", err)
 	}
 
-	if err := s.DB.MarkFeedFetched(ctx, feed.ID); err != nil {
-		return fmt.Errorf("could not mark feed as fetched: %w", err)
+	if len(rows) == 0 && !asJSON {
+		fmt.Println("No posts found.")
+		return nil
 	}
 
-	fmt.Printf("Fetching feed: %s (%s)\n", feed.Name, feed.Url)
-
-	parsedFeed, err := rss.FetchFeed(ctx, feed.Url)
+	postIDs := make([]uuid.UUID, len(rows))
+	for i, p := range rows {
+		postIDs[i] = p.ID
+	}
+	enclosures, err := s.DB.GetEnclosuresForPosts(ctx, postIDs)
 	if err != nil {
-		return fmt.Errorf("could not fetch rss feed: %w", err)
+		return fmt.Errorf("could not get enclosures: %w", err)
+	}
+	media := make(map[uuid.UUID][]database.Enclosure)
+	for _, e := range enclosures {
+		media[e.PostID] = append(media[e.PostID], e)
 	}
 
-	for _, item := range parsedFeed.Channel.Items {
-		publishedAt := sql.NullTime{}
-		if t, err := parsePublishedTime(item.PubDate); err == nil {
-			publishedAt = sql.NullTime{
-				Time:  t,
-				Valid: true,
-			}
-		} else {
-			log.Printf("could not parse pubDate %q for feed: %s: %v\n", item.PubDate, feed.Url, err)
+	if asJSON {
+		posts := make([]postJSON, 0, len(rows))
+		for _, p := range rows {
+			posts = append(posts, newPostJSON(p, media[p.ID]))
 		}
+		return writeJSON(posts)
+	}
 
-		now := time.Now()
-
-		err = s.DB.CreatePost(ctx, database.CreatePostParams{
-			ID:        uuid.New(),
-			CreatedAt: now,
-			UpdatedAt: now,
-			Title:     item.Title,
-			Url:       item.Link,
-			Description: sql.NullString{
-				String: item.Description,
-				Valid:  item.Description != "",
-			},
-			PublishedAt: publishedAt,
-			FeedID:      feed.ID,
-		})
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
-				continue
+	for _, p := range rows {
+		published := "unknown"
+		if p.PublishedAt.Valid {
+			published = p.PublishedAt.Time.Format(time.RFC1123)
+		}
+
+		fmt.Printf("Title: %s\nURL: %s\nPublished: %s\n", p.Title, p.Url, published)
+		if p.Author.Valid {
+			fmt.Printf("Author: %s\n", p.Author.String)
+		}
+		if len(p.Categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(p.Categories, ", "))
+		}
+		if p.ImageUrl.Valid {
+			fmt.Printf("Image: %s\n", p.ImageUrl.String)
+		}
+		for _, e := range media[p.ID] {
+			fmt.Printf("Media: %s\n", describeEnclosure(e))
+		}
+		if full {
+			body := p.Content
+			if !body.Valid {
+				body = p.Description
+			}
+			if body.Valid {
+				fmt.Printf("\n%s\n", body.String)
 			}
-			log.Printf("error saving post (feed %s): %v\n", feed.Url, err)
 		}
+		fmt.Println()
 	}
-
 	return nil
 }
 
-func parsePublishedTime(raw string) (time.Time, error) {
-	raw = strings.TrimSpace(raw)
-	if raw == "" {
-		return time.Time{}, fmt.Errorf("empty pubDate")
+func (s *State) HandlerPostHistory(ctx context.Context, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: post-history <post-url>")
 	}
 
-	layouts := []string{
-		time.RFC1123Z,
-		time.RFC1123,
-		time.RFC822Z,
-		time.RFC822,
-		time.RFC3339,
+	post, err := s.DB.GetPostByURL(ctx, strings.TrimSpace(cmd.Args[0]))
+	if err != nil {
+		return fmt.Errorf("post url does not exist: %v", err)
 	}
 
-	for _, layout := range layouts {
-		if t, err := time.Parse(layout, raw); err == nil {
-			return t, nil
+	revisions, err := s.DB.GetPostRevisions(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("could not get post history: %w", err)
+	}
+
+	fmt.Printf("%s (%s)\n", post.Title, post.Url)
+	if len(revisions) == 0 {
+		fmt.Println("No earlier versions recorded.")
+		if !s.Cfg.KeepPostHistory {
+			fmt.Println("Set keep_post_history in the config file to record them.")
 		}
+		return nil
 	}
 
-	return time.Time{}, fmt.Errorf("could not parse pubDate: %q", raw)
+	versions := make([]database.PostRevision, 0, len(revisions)+1)
+	versions = append(versions, revisions...)
+	versions = append(versions, database.PostRevision{
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Description: post.Description,
+		Content:     post.Content,
+		PublishedAt: post.PublishedAt,
+	})
+
+	for i := 1; i < len(versions); i++ {
+		prev, cur := versions[i-1], versions[i]
+		fmt.Printf("\n--- %s\n+++ %s\n", prev.UpdatedAt.Format(time.RFC1123), cur.UpdatedAt.Format(time.RFC1123))
+		printFieldDiff("title", prev.Title, cur.Title)
+		printFieldDiff("published", formatPublished(prev.PublishedAt), formatPublished(cur.PublishedAt))
+		printFieldDiff("description", prev.Description.String, cur.Description.String)
+		printFieldDiff("content", prev.Content.String, cur.Content.String)
+	}
+	return nil
 }
 
-func HandlerBrowse(s *State, cmd Command, user database.User) error {
-	limit := 2
-	if len(cmd.Args) >= 1 {
-		n, err := strconv.Atoi(cmd.Args[0])
-		if err != nil {
-			return fmt.Errorf("invalid limit %q", err)
+// printFieldDiff prints the changed lines of one post field, if any.
+func printFieldDiff(field, before, after string) {
+	if before == after {
+		return
+	}
+	fmt.Printf("%s:\n", field)
+	for _, line := range diffLines(before, after) {
+		if !strings.HasPrefix(line, " ") {
+			fmt.Println(line)
 		}
-		limit = n
 	}
+}
 
-	ctx := context.Background()
-
-	rows, err := s.DB.GetPostsForUser(ctx, database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
-	if err != nil {
-		return fmt.Errorf("could not get posts: %w", err)
+func formatPublished(t sql.NullTime) string {
+	if !t.Valid {
+		return "unknown"
 	}
+	return t.Time.Format(time.RFC1123)
+}
 
-	if len(rows) == 0 {
-		fmt.Println("No posts found.")
-		return nil
+// describeEnclosure formats an enclosure as its URL followed by whatever
+// episode details the feed provided.
+func describeEnclosure(e database.Enclosure) string {
+	var details []string
+	if e.Season.Valid && e.Episode.Valid {
+		details = append(details, fmt.Sprintf("S%dE%d", e.Season.Int32, e.Episode.Int32))
+	} else if e.Episode.Valid {
+		details = append(details, fmt.Sprintf("episode %d", e.Episode.Int32))
+	}
+	if e.MimeType.Valid {
+		details = append(details, e.MimeType.String)
+	}
+	if e.DurationSeconds.Valid {
+		details = append(details, (time.Duration(e.DurationSeconds.Int32) * time.Second).String())
+	}
+	if e.LengthBytes.Valid {
+		details = append(details, fmt.Sprintf("%.1f MB", float64(e.LengthBytes.Int64)/(1<<20)))
 	}
 
-	for _, p := range rows {
-		published := "unknown"
-		if p.PublishedAt.Valid {
-			published = p.PublishedAt.Time.Format(time.RFC1123)
-		}
-
-		fmt.Printf("Title: %s\nURL: %s\nPublished: %s\n\n", p.Title, p.Url, published)
+	if len(details) == 0 {
+		return e.Url
 	}
-	return nil
+	return fmt.Sprintf("%s (%s)", e.Url, strings.Join(details, ", "))
 }
@@ -0,0 +1,59 @@
+package commands
+
+import "strings"
+
+// diffLines returns a line diff turning a into b. Each line is prefixed
+// with "-" when removed, "+" when added or " " when unchanged.
+func diffLines(a, b string) []string {
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, " "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "-"+x[i])
+			i++
+		default:
+			out = append(out, "+"+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "-"+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+"+y[j])
+	}
+	return out
+}
+
+// splitLines splits s into lines, treating an empty s as no lines rather
+// than one empty line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
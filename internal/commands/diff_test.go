@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{
+			name: "identical",
+			a:    "one\ntwo",
+			b:    "one\ntwo",
+			want: []string{" one", " two"},
+		},
+		{
+			name: "line changed",
+			a:    "title\nold body\nfooter",
+			b:    "title\nnew body\nfooter",
+			want: []string{" title", "-old body", "+new body", " footer"},
+		},
+		{
+			name: "line added at the end",
+			a:    "one",
+			b:    "one\ntwo",
+			want: []string{" one", "+two"},
+		},
+		{
+			name: "line removed at the start",
+			a:    "intro\none\ntwo",
+			b:    "one\ntwo",
+			want: []string{"-intro", " one", " two"},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "text\nmore",
+			want: []string{"+text", "+more"},
+		},
+		{
+			name: "to empty",
+			a:    "text",
+			b:    "",
+			want: []string{"-text"},
+		},
+		{
+			name: "common lines are kept across edits",
+			a:    "a\nb\nc\nd",
+			b:    "a\nc\nd\ne",
+			want: []string{" a", "-b", " c", " d", "+e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/angelchiav/blog-aggregator-go/internal/database"
+	"github.com/google/uuid"
+)
+
+// postJSON is a post as printed by browse --json.
+type postJSON struct {
+	ID          uuid.UUID       `json:"id"`
+	Title       string          `json:"title"`
+	URL         string          `json:"url"`
+	PublishedAt *time.Time      `json:"published_at,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Categories  []string        `json:"categories,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Content     string          `json:"content,omitempty"`
+	ImageURL    string          `json:"image_url,omitempty"`
+	Feed        postFeedJSON    `json:"feed"`
+	Enclosures  []enclosureJSON `json:"enclosures,omitempty"`
+}
+
+type postFeedJSON struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	ImageURL string    `json:"image_url,omitempty"`
+}
+
+type enclosureJSON struct {
+	URL             string `json:"url"`
+	MimeType        string `json:"mime_type,omitempty"`
+	LengthBytes     int64  `json:"length_bytes,omitempty"`
+	DurationSeconds int32  `json:"duration_seconds,omitempty"`
+	Season          int32  `json:"season,omitempty"`
+	Episode         int32  `json:"episode,omitempty"`
+}
+
+// feedJSON is a feed as printed by feeds --json.
+type feedJSON struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	AddedBy       string     `json:"added_by,omitempty"`
+	Status        string     `json:"status"`
+	SiteURL       string     `json:"site_url,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	Generator     string     `json:"generator,omitempty"`
+	IconURL       string     `json:"icon_url,omitempty"`
+	ImageURL      string     `json:"image_url,omitempty"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+}
+
+func newPostJSON(p database.GetPostsForUserRow, enclosures []database.Enclosure) postJSON {
+	out := postJSON{
+		ID:          p.ID,
+		Title:       p.Title,
+		URL:         p.Url,
+		PublishedAt: nullTime(p.PublishedAt),
+		Author:      p.Author.String,
+		Categories:  p.Categories,
+		Description: p.Description.String,
+		Content:     p.Content.String,
+		ImageURL:    p.ImageUrl.String,
+		Feed: postFeedJSON{
+			ID:       p.FeedID,
+			Name:     p.FeedName,
+			ImageURL: p.FeedImageUrl.String,
+		},
+	}
+	for _, e := range enclosures {
+		out.Enclosures = append(out.Enclosures, enclosureJSON{
+			URL:             e.Url,
+			MimeType:        e.MimeType.String,
+			LengthBytes:     e.LengthBytes.Int64,
+			DurationSeconds: e.DurationSeconds.Int32,
+			Season:          e.Season.Int32,
+			Episode:         e.Episode.Int32,
+		})
+	}
+	return out
+}
+
+func newFeedJSON(f database.Feed, addedBy string) feedJSON {
+	return feedJSON{
+		ID:            f.ID,
+		Name:          f.Name,
+		URL:           f.Url,
+		AddedBy:       addedBy,
+		Status:        f.Status,
+		SiteURL:       f.SiteUrl.String,
+		Description:   f.Description.String,
+		Language:      f.Language.String,
+		Generator:     f.Generator.String,
+		IconURL:       f.IconUrl.String,
+		ImageURL:      f.ImageUrl.String,
+		LastFetchedAt: nullTime(f.LastFetchedAt),
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// writeJSON prints v as indented JSON on stdout.
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
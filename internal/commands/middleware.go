@@ -8,9 +8,9 @@ import (
 )
 
 func MiddlewareLoggedIn(
-	handler func(s *State, cmd Command, user database.User) error,
-) func(*State, Command) error {
-	return func(s *State, cmd Command) error {
+	handler func(ctx context.Context, s *State, cmd Command, user database.User) error,
+) func(context.Context, *State, Command) error {
+	return func(ctx context.Context, s *State, cmd Command) error {
 		if s == nil || s.Cfg == nil {
 			return fmt.Errorf("internal error: missing state/config")
 		}
@@ -21,11 +21,11 @@ func MiddlewareLoggedIn(
 			return fmt.Errorf("you must be logged in to use: '%s'", cmd.Name)
 		}
 
-		u, err := s.DB.GetUserByName(context.Background(), current)
+		u, err := s.DB.GetUserByName(ctx, current)
 		if err != nil {
 			return fmt.Errorf("failed to load current user '%s': %w", current, err)
 		}
 
-		return handler(s, cmd, u)
+		return handler(ctx, s, cmd, u)
 	}
 }
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/angelchiav/blog-aggregator-go/internal/database"
+	"github.com/angelchiav/blog-aggregator-go/internal/rss"
+)
+
+const (
+	minRefreshInterval     = 15 * time.Minute
+	maxRefreshInterval     = 24 * time.Hour
+	defaultRefreshInterval = time.Hour
+
+	// maxDeclaredInterval bounds how long a publisher's declared interval
+	// can keep a feed from being polled.
+	maxDeclaredInterval = 7 * 24 * time.Hour
+
+	// postingSample is how many recent posts are used to estimate how
+	// often a feed publishes.
+	postingSample = 10
+)
+
+// refreshInterval polls at half the observed gap between posts, clamped
+// to [minRefreshInterval, maxRefreshInterval], but never more often than
+// the publisher's declared interval. A declared interval longer than the
+// clamp wins, up to maxDeclaredInterval, so a weekly <ttl> is honored.
+func refreshInterval(observed, declared time.Duration) time.Duration {
+	interval := defaultRefreshInterval
+	if observed > 0 {
+		interval = observed / 2
+	}
+	interval = min(max(interval, minRefreshInterval), maxRefreshInterval)
+	return max(interval, min(declared, maxDeclaredInterval))
+}
+
+// observedPostingInterval estimates the average gap between posts. The
+// window runs up to now rather than to the newest post, so a feed that has
+// gone quiet is polled less and less often.
+func observedPostingInterval(now time.Time, published []sql.NullTime) time.Duration {
+	if len(published) < 2 {
+		return 0
+	}
+	oldest := published[len(published)-1].Time
+	return now.Sub(oldest) / time.Duration(len(published))
+}
+
+// skipQuietWindows moves t forward, an hour at a time, until it falls
+// outside the feed's skipHours/skipDays, which RSS defines in GMT. If every
+// hour is excluded the windows are ignored. This is the only place the
+// windows are enforced: agg claims feeds by next_fetch_at alone.
+func skipQuietWindows(t time.Time, skipHours []int32, skipDays []string) time.Time {
+	quiet := func(t time.Time) bool {
+		utc := t.UTC()
+		if slices.Contains(skipHours, int32(utc.Hour())) {
+			return true
+		}
+		return slices.Contains(skipDays, utc.Weekday().String())
+	}
+
+	candidate := t
+	for range 7 * 24 {
+		if !quiet(candidate) {
+			return candidate
+		}
+		candidate = candidate.UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// scheduleNextFetch sets next_fetch_at after a successful fetch. parsed is
+// nil when the server answered 304, in which case the stored publisher
+// hints are reused.
+func scheduleNextFetch(ctx context.Context, s *State, feed database.Feed, parsed *rss.RRSFeed) {
+	declared := time.Duration(feed.DeclaredIntervalSeconds.Int32) * time.Second
+	skipHours, skipDays := feed.SkipHours, feed.SkipDays
+	if parsed != nil {
+		declared = parsed.DeclaredInterval()
+
+		skipHours = []int32{}
+		for _, h := range parsed.QuietHours() {
+			skipHours = append(skipHours, int32(h))
+		}
+		skipDays = []string{}
+		for _, d := range parsed.QuietDays() {
+			skipDays = append(skipDays, d.String())
+		}
+	}
+
+	published, err := s.DB.GetRecentPublishTimes(ctx, database.GetRecentPublishTimesParams{
+		FeedID: feed.ID,
+		Limit:  postingSample,
+	})
+	if err != nil {
+		log.Printf("could not load posting history for feed %s: %v\n", feed.Url, err)
+	}
+
+	now := time.Now()
+	next := now.Add(refreshInterval(observedPostingInterval(now, published), declared))
+	next = skipQuietWindows(next, skipHours, skipDays)
+
+	err = s.DB.UpdateFeedSchedule(ctx, database.UpdateFeedScheduleParams{
+		ID: feed.ID,
+		NextFetchAt: sql.NullTime{
+			Time:  next,
+			Valid: true,
+		},
+		DeclaredIntervalSeconds: sql.NullInt32{
+			Int32: int32(declared.Seconds()),
+			Valid: declared > 0,
+		},
+		SkipHours: skipHours,
+		SkipDays:  skipDays,
+	})
+	if err != nil {
+		log.Printf("could not schedule next fetch for feed %s: %v\n", feed.Url, err)
+	}
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		observed time.Duration
+		declared time.Duration
+		want     time.Duration
+	}{
+		{name: "no history", want: defaultRefreshInterval},
+		{name: "half the observed gap", observed: 4 * time.Hour, want: 2 * time.Hour},
+		{name: "frequent posts are clamped", observed: time.Minute, want: minRefreshInterval},
+		{name: "rare posts are clamped", observed: 30 * 24 * time.Hour, want: maxRefreshInterval},
+		{name: "declared interval is a floor", observed: 4 * time.Hour, declared: 6 * time.Hour, want: 6 * time.Hour},
+		{name: "shorter declared interval is ignored", observed: 4 * time.Hour, declared: time.Hour, want: 2 * time.Hour},
+		{name: "declared interval below the minimum", declared: time.Minute, want: defaultRefreshInterval},
+		{name: "weekly ttl beats the daily cap", observed: time.Hour, declared: 7 * 24 * time.Hour, want: 7 * 24 * time.Hour},
+		{name: "declared interval is capped", declared: 365 * 24 * time.Hour, want: maxDeclaredInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refreshInterval(tt.observed, tt.declared); got != tt.want {
+				t.Errorf("refreshInterval(%s, %s) = %s, want %s", tt.observed, tt.declared, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObservedPostingInterval(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) sql.NullTime {
+		return sql.NullTime{Time: now.Add(-d), Valid: true}
+	}
+
+	tests := []struct {
+		name      string
+		published []sql.NullTime
+		want      time.Duration
+	}{
+		{name: "no posts", want: 0},
+		{name: "one post", published: []sql.NullTime{ago(time.Hour)}, want: 0},
+		{
+			name:      "average gap up to now",
+			published: []sql.NullTime{ago(time.Hour), ago(2 * time.Hour), ago(3 * time.Hour)},
+			want:      time.Hour,
+		},
+		{
+			name:      "quiet feed stretches the gap",
+			published: []sql.NullTime{ago(10 * 24 * time.Hour), ago(10*24*time.Hour + time.Hour)},
+			want:      (10*24*time.Hour + time.Hour) / 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := observedPostingInterval(now, tt.published); got != tt.want {
+				t.Errorf("observedPostingInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipQuietWindows(t *testing.T) {
+	// May 10, 2024 is a Friday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+	allHours := make([]int32, 24)
+	for h := range allHours {
+		allHours[h] = int32(h)
+	}
+
+	tests := []struct {
+		name      string
+		t         time.Time
+		skipHours []int32
+		skipDays  []string
+		want      time.Time
+	}{
+		{name: "no windows", t: at(10, 3, 20), want: at(10, 3, 20)},
+		{name: "outside the windows", t: at(10, 3, 20), skipHours: []int32{5}, want: at(10, 3, 20)},
+		{name: "quiet hour moves to the next hour", t: at(10, 3, 20), skipHours: []int32{3}, want: at(10, 4, 0)},
+		{name: "consecutive quiet hours", t: at(10, 22, 5), skipHours: []int32{22, 23, 0}, want: at(11, 1, 0)},
+		{name: "quiet day moves to the next day", t: at(10, 9, 0), skipDays: []string{"Friday"}, want: at(11, 0, 0)},
+		{
+			name:      "hours and days combine",
+			t:         at(11, 10, 0),
+			skipHours: []int32{0, 1},
+			skipDays:  []string{"Saturday"},
+			want:      at(12, 2, 0),
+		},
+		{
+			name:      "hours are read in GMT",
+			t:         time.Date(2024, 5, 10, 5, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			skipHours: []int32{3},
+			want:      at(10, 4, 0),
+		},
+		{name: "fully quiet feed keeps the time", t: at(10, 3, 20), skipHours: allHours, want: at(10, 3, 20)},
+		{
+			name:     "every day quiet keeps the time",
+			t:        at(10, 3, 20),
+			skipDays: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+			want:     at(10, 3, 20),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipQuietWindows(tt.t, tt.skipHours, tt.skipDays); !got.Equal(tt.want) {
+				t.Errorf("skipQuietWindows(%s) = %s, want %s", tt.t, got, tt.want)
+			}
+		})
+	}
+}
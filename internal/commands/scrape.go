@@ -0,0 +1,571 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/angelchiav/blog-aggregator-go/internal/database"
+	"github.com/angelchiav/blog-aggregator-go/internal/rss"
+	"github.com/google/uuid"
+)
+
+// feedLease is how long a claimed feed stays reserved for the claiming
+// aggregator. It only matters if that process dies mid-fetch; otherwise the
+// lease is released as soon as the fetch finishes.
+const feedLease = 10 * time.Minute
+
+// maxFetchTime caps a download whatever fetch.timeout is set to, so the
+// lease cannot run out while the feed is still being fetched.
+const maxFetchTime = feedLease / 2
+
+// Values of feeds.status. Only active feeds are claimed by agg; paused
+// and dead feeds wait for enable-feed.
+const (
+	feedStatusActive = "active"
+	feedStatusPaused = "paused"
+	feedStatusDead   = "dead"
+)
+
+// scrapeSummary counts what one or more scrape cycles processed.
+type scrapeSummary struct {
+	Claimed  int
+	Fetched  int
+	NewPosts int
+	Failed   int
+	Aborted  int
+}
+
+func (s *scrapeSummary) add(other scrapeSummary) {
+	s.Claimed += other.Claimed
+	s.Fetched += other.Fetched
+	s.NewPosts += other.NewPosts
+	s.Failed += other.Failed
+	s.Aborted += other.Aborted
+}
+
+// scrapeFeeds claims up to concurrency feeds not fetched since
+// fetchedBefore and fetches them in parallel. Claiming is a single atomic
+// query that skips rows locked or leased by another aggregator, so several
+// processes can share the work.
+//
+// Cancelling ctx aborts downloads in flight and skips feeds not yet
+// started; their leases are still released so other aggregators can pick
+// them up.
+func scrapeFeeds(ctx context.Context, s *State, concurrency int, fetchedBefore time.Time) (scrapeSummary, error) {
+	var summary scrapeSummary
+
+	feeds, err := s.DB.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		LeaseSeconds:  feedLease.Seconds(),
+		FetchedBefore: fetchedBefore,
+		MaxFeeds:      int32(concurrency),
+	})
+	if err != nil {
+		return summary, fmt.Errorf("could not claim feeds to fetch: %w", err)
+	}
+	summary.Claimed = len(feeds)
+
+	// Bookkeeping runs to completion even after cancellation.
+	bookCtx := context.WithoutCancel(ctx)
+
+	var (
+		mu       sync.Mutex
+		failures []error
+	)
+
+	jobs := make(chan database.Feed)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				if ctx.Err() != nil {
+					releaseFeedLease(bookCtx, s, feed)
+					mu.Lock()
+					summary.Aborted++
+					mu.Unlock()
+					continue
+				}
+
+				report, err := processFeed(ctx, s, feed)
+				aborted := err != nil && ctx.Err() != nil
+				releaseFeedLease(bookCtx, s, feed)
+
+				mu.Lock()
+				switch {
+				case aborted:
+					summary.Aborted++
+				case err != nil:
+					summary.Failed++
+					failures = append(failures, fmt.Errorf("%s: %w", feed.Url, err))
+				default:
+					summary.Fetched++
+					summary.NewPosts += report.NewPosts
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, feed := range feeds {
+		jobs <- feed
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return summary, errors.Join(failures...)
+}
+
+// processFeed scrapes a feed and records the attempt. Attempts cut short by
+// cancellation are not recorded against the feed.
+func processFeed(ctx context.Context, s *State, feed database.Feed) (fetchReport, error) {
+	started := time.Now()
+	report, err := scrapeFeed(ctx, s, feed)
+	if err != nil && ctx.Err() != nil {
+		return report, err
+	}
+
+	bookCtx := context.WithoutCancel(ctx)
+	recordFetchOutcome(bookCtx, s, feed, err)
+	logFetch(bookCtx, s, feed, started, report, err)
+
+	if err == nil && report.MovedTo != "" {
+		if err := relocateFeed(bookCtx, s, feed, report.MovedTo); err != nil {
+			log.Printf("could not move feed %s to %s: %v\n", feed.Url, report.MovedTo, err)
+		}
+	}
+	return report, err
+}
+
+// relocateFeed points a permanently redirected feed at its new URL. If
+// another feed already uses that URL, the two are merged: follows, posts
+// and fetch history move to the existing feed and this one is deleted.
+func relocateFeed(ctx context.Context, s *State, feed database.Feed, newURL string) error {
+	err := s.DB.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+		ID:  feed.ID,
+		Url: newURL,
+	})
+	if err == nil {
+		fmt.Printf("Feed moved: %s -> %s\n", feed.Url, newURL)
+		return nil
+	}
+	if !strings.Contains(err.Error(), "duplicate key") {
+		return err
+	}
+
+	target, err := s.DB.GetFeedByURL(ctx, newURL)
+	if err != nil {
+		return fmt.Errorf("could not load feed at %s: %w", newURL, err)
+	}
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := s.DB.WithTx(tx)
+
+	if err := q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		ToFeedID:   target.ID,
+		FromFeedID: feed.ID,
+	}); err != nil {
+		return fmt.Errorf("could not move follows: %w", err)
+	}
+	if err := q.MovePosts(ctx, database.MovePostsParams{
+		ToFeedID:   target.ID,
+		FromFeedID: feed.ID,
+	}); err != nil {
+		return fmt.Errorf("could not move posts: %w", err)
+	}
+	if err := q.MoveFeedFetches(ctx, database.MoveFeedFetchesParams{
+		ToFeedID:   target.ID,
+		FromFeedID: feed.ID,
+	}); err != nil {
+		return fmt.Errorf("could not move fetch history: %w", err)
+	}
+	if err := q.DeleteFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("could not delete old feed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Feed merged: %s -> %s (%s)\n", feed.Url, target.Url, target.Name)
+	return nil
+}
+
+func releaseFeedLease(ctx context.Context, s *State, feed database.Feed) {
+	if err := s.DB.ReleaseFeedLease(ctx, feed.ID); err != nil {
+		log.Printf("could not release lease on feed %s: %v\n", feed.Url, err)
+	}
+}
+
+// recordFetchOutcome updates the failure counters on a feed, disabling it
+// once it reaches the configured number of consecutive failures. A server
+// asking us to back off with Retry-After is recorded on the feed, and a 429
+// does not count as a failure.
+func recordFetchOutcome(ctx context.Context, s *State, feed database.Feed, fetchErr error) {
+	var statusErr *rss.StatusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > 0 {
+		until := time.Now().Add(statusErr.RetryAfter)
+		err := s.DB.SetFeedRateLimited(ctx, database.SetFeedRateLimitedParams{
+			ID:               feed.ID,
+			RateLimitedUntil: sql.NullTime{Time: until, Valid: true},
+		})
+		if err != nil {
+			log.Printf("could not record rate limit for feed %s: %v\n", feed.Url, err)
+		}
+		log.Printf("feed %s asked us to retry after %s\n", feed.Url, until.Format(time.RFC1123))
+
+		if statusErr.StatusCode == http.StatusTooManyRequests {
+			return
+		}
+	}
+
+	var blockedErr *rss.HostBlockedError
+	if errors.As(fetchErr, &blockedErr) {
+		err := s.DB.SetFeedRateLimited(ctx, database.SetFeedRateLimitedParams{
+			ID:               feed.ID,
+			RateLimitedUntil: sql.NullTime{Time: blockedErr.Until, Valid: true},
+		})
+		if err != nil {
+			log.Printf("could not record rate limit for feed %s: %v\n", feed.Url, err)
+		}
+		return
+	}
+
+	if fetchErr == nil {
+		if feed.ConsecutiveFailures > 0 {
+			if err := s.DB.RecordFeedSuccess(ctx, feed.ID); err != nil {
+				log.Printf("could not reset failures for feed %s: %v\n", feed.Url, err)
+			}
+		}
+		return
+	}
+
+	// 410 Gone is the publisher telling us the feed will not come back.
+	gone := errors.As(fetchErr, &statusErr) && statusErr.StatusCode == http.StatusGone
+
+	updated, err := s.DB.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError: sql.NullString{
+			String: fetchErr.Error(),
+			Valid:  true,
+		},
+		Gone:             gone,
+		DeadAfterSeconds: s.Cfg.DeadFeedAge().Seconds(),
+		MaxFailures:      int32(s.Cfg.FeedFailureLimit()),
+		ID:               feed.ID,
+	})
+	if err != nil {
+		log.Printf("could not record failure for feed %s: %v\n", feed.Url, err)
+		return
+	}
+
+	if updated.Status != feed.Status {
+		switch updated.Status {
+		case feedStatusDead:
+			log.Printf("feed %s marked dead (failing since %s)\n", feed.Url, updated.FailingSince.Time.Format(time.RFC1123))
+		case feedStatusPaused:
+			log.Printf("feed %s paused after %d consecutive failures\n", feed.Url, updated.ConsecutiveFailures)
+		}
+	}
+}
+
+// fetchReport summarizes one scrape of a feed.
+type fetchReport struct {
+	StatusCode  int
+	NotModified bool
+	NewPosts    int
+	Updated     int
+	Existing    int
+
+	// MovedTo is set when the feed answered through permanent redirects
+	// to a different URL.
+	MovedTo string
+}
+
+// logFetch stores the attempt in feed_fetches for the feed-log command.
+func logFetch(ctx context.Context, s *State, feed database.Feed, started time.Time, report fetchReport, fetchErr error) {
+	statusCode := report.StatusCode
+	var statusErr *rss.StatusError
+	if errors.As(fetchErr, &statusErr) {
+		statusCode = statusErr.StatusCode
+	}
+
+	errText := sql.NullString{}
+	if fetchErr != nil {
+		errText = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	err := s.DB.CreateFeedFetch(ctx, database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		FeedID:     feed.ID,
+		StartedAt:  started,
+		DurationMs: int32(time.Since(started).Milliseconds()),
+		StatusCode: sql.NullInt32{
+			Int32: int32(statusCode),
+			Valid: statusCode != 0,
+		},
+		NewPosts: int32(report.NewPosts),
+		Error:    errText,
+	})
+	if err != nil {
+		log.Printf("could not log fetch of feed %s: %v\n", feed.Url, err)
+	}
+}
+
+// scrapeFeed downloads one feed and stores its posts. Only the download
+// honours cancellation; once a body is in hand it is stored in full.
+func scrapeFeed(ctx context.Context, s *State, feed database.Feed) (fetchReport, error) {
+	var report fetchReport
+
+	fmt.Printf("Fetching feed: %s (%s)\n", feed.Name, feed.Url)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, maxFetchTime)
+	defer cancel()
+
+	result, err := s.Fetcher.Fetch(fetchCtx, feed.Url, rss.Validators{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+	})
+	if err != nil {
+		return report, fmt.Errorf("could not fetch rss feed: %w", err)
+	}
+	report.StatusCode = result.StatusCode
+	if result.PermanentRedirect && result.FinalURL != feed.Url {
+		report.MovedTo = result.FinalURL
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	if err := s.DB.UpdateFeedValidators(ctx, database.UpdateFeedValidatorsParams{
+		ID: feed.ID,
+		Etag: sql.NullString{
+			String: result.Validators.ETag,
+			Valid:  result.Validators.ETag != "",
+		},
+		LastModified: sql.NullString{
+			String: result.Validators.LastModified,
+			Valid:  result.Validators.LastModified != "",
+		},
+	}); err != nil {
+		log.Printf("could not store cache validators for feed %s: %v\n", feed.Url, err)
+	}
+
+	if result.NotModified {
+		fmt.Printf("Feed not modified: %s\n", feed.Url)
+		report.NotModified = true
+		scheduleNextFetch(ctx, s, feed, nil)
+		return report, nil
+	}
+
+	channel := result.Feed.Channel
+	if err := s.DB.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		SiteUrl:     optionalString(channel.Link),
+		Description: optionalString(channel.Description),
+		Language:    optionalString(channel.Language),
+		Generator:   optionalString(channel.Generator),
+		IconUrl:     optionalString(channel.Icon),
+		ImageUrl:    optionalString(result.Feed.ImageURL()),
+	}); err != nil {
+		log.Printf("could not store metadata for feed %s: %v\n", feed.Url, err)
+	}
+
+	if result.Feed.SkippedItems > 0 {
+		log.Printf("skipped %d malformed items in feed: %s\n", result.Feed.SkippedItems, feed.Url)
+	}
+
+	for _, item := range result.Feed.Channel.Items {
+		publishedAt := sql.NullTime{}
+		if t, err := parsePublishedTime(item.PubDate); err == nil {
+			publishedAt = sql.NullTime{
+				Time:  t,
+				Valid: true,
+			}
+		} else {
+			log.Printf("could not parse pubDate %q for feed: %s: %v\n", item.PubDate, feed.Url, err)
+		}
+
+		if item.Link == "" {
+			log.Printf("skipping item without a link (feed %s): %q\n", feed.Url, item.Title)
+			continue
+		}
+
+		// Read the stored version first, to tell edits from fields being
+		// filled in and to keep it as a revision.
+		var previous *database.Post
+		if p, err := s.DB.GetPostByURL(ctx, item.Link); err == nil {
+			previous = &p
+		}
+
+		// pq sends a nil slice as NULL, which posts.categories rejects.
+		categories := item.Categories
+		if categories == nil {
+			categories = []string{}
+		}
+
+		now := time.Now()
+		params := database.UpsertPostParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title:     item.Title,
+			Url:       item.Link,
+			Description: sql.NullString{
+				String: item.Description,
+				Valid:  item.Description != "",
+			},
+			PublishedAt: publishedAt,
+			FeedID:      feed.ID,
+			Guid: sql.NullString{
+				String: item.GUID,
+				Valid:  item.GUID != "",
+			},
+			Author: sql.NullString{
+				String: item.Author,
+				Valid:  item.Author != "",
+			},
+			Categories: categories,
+			Content: sql.NullString{
+				String: item.Content,
+				Valid:  item.Content != "",
+			},
+			ImageUrl: sql.NullString{
+				String: item.Image,
+				Valid:  item.Image != "",
+			},
+		}
+
+		row, err := s.DB.UpsertPost(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Unchanged, or the URL belongs to a post of another feed.
+			report.Existing++
+			continue
+		}
+		if err != nil {
+			log.Printf("error saving post (feed %s): %v\n", feed.Url, err)
+			continue
+		}
+
+		switch {
+		case row.Inserted:
+			report.NewPosts++
+		case previous != nil && !postEdited(*previous, params):
+			report.Existing++
+		default:
+			report.Updated++
+			if previous != nil && s.Cfg.KeepPostHistory {
+				recordRevision(ctx, s, *previous, params)
+			}
+		}
+
+		storeEnclosures(ctx, s, row.ID, item)
+	}
+
+	scheduleNextFetch(ctx, s, feed, result.Feed)
+
+	return report, nil
+}
+
+// postEdited reports whether an upsert changes what the post history
+// keeps. Posts stored before content was saved, or without a publish date,
+// are not edited when those fields are first filled in.
+func postEdited(previous database.Post, updated database.UpsertPostParams) bool {
+	if previous.Title != updated.Title || previous.Description != updated.Description {
+		return true
+	}
+	if previous.Content.Valid && previous.Content != updated.Content {
+		return true
+	}
+	return previous.PublishedAt.Valid && updated.PublishedAt.Valid &&
+		!previous.PublishedAt.Time.Equal(updated.PublishedAt.Time)
+}
+
+// recordRevision keeps the replaced version of an edited post.
+func recordRevision(ctx context.Context, s *State, previous database.Post, updated database.UpsertPostParams) {
+	err := s.DB.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+		ID:          uuid.New(),
+		PostID:      previous.ID,
+		UpdatedAt:   previous.UpdatedAt,
+		ReplacedAt:  updated.UpdatedAt,
+		Title:       previous.Title,
+		Description: previous.Description,
+		Content:     previous.Content,
+		PublishedAt: previous.PublishedAt,
+	})
+	if err != nil {
+		log.Printf("could not record revision of post %s: %v\n", previous.Url, err)
+	}
+}
+
+// storeEnclosures saves the media files attached to a post, with the
+// item's iTunes episode details. Enclosures already stored are kept.
+func storeEnclosures(ctx context.Context, s *State, postID uuid.UUID, item rss.RSSItem) {
+	duration := int32(item.PlayTime().Seconds())
+	episode := int32(item.EpisodeNumber())
+	season := int32(item.SeasonNumber())
+
+	for _, e := range item.Enclosures {
+		size := e.Size()
+		err := s.DB.CreateEnclosure(ctx, database.CreateEnclosureParams{
+			ID:        uuid.New(),
+			PostID:    postID,
+			CreatedAt: time.Now(),
+			Url:       e.URL,
+			MimeType: sql.NullString{
+				String: e.Type,
+				Valid:  e.Type != "",
+			},
+			LengthBytes:     sql.NullInt64{Int64: size, Valid: size > 0},
+			DurationSeconds: sql.NullInt32{Int32: duration, Valid: duration > 0},
+			Episode:         sql.NullInt32{Int32: episode, Valid: episode > 0},
+			Season:          sql.NullInt32{Int32: season, Valid: season > 0},
+		})
+		if err != nil {
+			log.Printf("error saving enclosure %s: %v\n", e.URL, err)
+		}
+	}
+}
+
+func parsePublishedTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("empty pubDate")
+	}
+
+	layouts := []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC822Z,
+		time.RFC822,
+		time.RFC3339,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02",
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("could not parse pubDate: %q", raw)
+}
+
+// optionalString stores empty strings as NULL.
+func optionalString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
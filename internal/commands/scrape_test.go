@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/angelchiav/blog-aggregator-go/internal/database"
+)
+
+func TestPostEdited(t *testing.T) {
+	day := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	text := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	date := func(t time.Time) sql.NullTime { return sql.NullTime{Time: t, Valid: true} }
+
+	stored := database.Post{
+		Title:       "title",
+		Description: text("description"),
+		Content:     text("content"),
+		PublishedAt: date(day),
+	}
+	// params returns the upsert of the stored post with edit applied.
+	params := func(edit func(*database.UpsertPostParams)) database.UpsertPostParams {
+		p := database.UpsertPostParams{
+			Title:       stored.Title,
+			Description: stored.Description,
+			Content:     stored.Content,
+			PublishedAt: stored.PublishedAt,
+		}
+		if edit != nil {
+			edit(&p)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		previous database.Post
+		updated  database.UpsertPostParams
+		want     bool
+	}{
+		{name: "unchanged", previous: stored, updated: params(nil)},
+		{
+			name:     "other fields changed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.Author = text("someone") }),
+		},
+		{
+			name:     "title changed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.Title = "new title" }),
+			want:     true,
+		},
+		{
+			name:     "description changed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.Description = text("new") }),
+			want:     true,
+		},
+		{
+			name:     "description removed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.Description = sql.NullString{} }),
+			want:     true,
+		},
+		{
+			name:     "content changed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.Content = text("new") }),
+			want:     true,
+		},
+		{
+			name:     "content filled in for an older post",
+			previous: database.Post{Title: stored.Title, Description: stored.Description, PublishedAt: stored.PublishedAt},
+			updated:  params(nil),
+		},
+		{
+			name:     "publish date changed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.PublishedAt = date(day.Add(time.Hour)) }),
+			want:     true,
+		},
+		{
+			name:     "publish date filled in",
+			previous: database.Post{Title: stored.Title, Description: stored.Description, Content: stored.Content},
+			updated:  params(nil),
+		},
+		{
+			name:     "publish date missing from the feed",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.PublishedAt = sql.NullTime{} }),
+		},
+		{
+			name:     "same publish date in another zone",
+			previous: stored,
+			updated:  params(func(p *database.UpsertPostParams) { p.PublishedAt = date(day.In(time.FixedZone("UTC+2", 2*60*60))) }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postEdited(tt.previous, tt.updated); got != tt.want {
+				t.Errorf("postEdited() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
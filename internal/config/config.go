@@ -6,11 +6,92 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
+// DefaultMaxFeedFailures is used when max_feed_failures is not set.
+const DefaultMaxFeedFailures = 10
+
+// DefaultDeadFeedAfter is used when dead_feed_after is not set.
+const DefaultDeadFeedAfter = 14 * 24 * time.Hour
+
 type Config struct {
 	DBURL       string `json:"db_url"`
 	CurrentUser string `json:"current_user_name"`
+
+	// MaxFeedFailures is how many consecutive fetch failures disable a
+	// feed. A negative value never disables feeds.
+	MaxFeedFailures int `json:"max_feed_failures,omitempty"`
+
+	// DeadFeedAfter is how long a feed may keep failing before it is
+	// marked dead. A negative value never marks feeds dead on age alone.
+	DeadFeedAfter Duration `json:"dead_feed_after,omitempty"`
+
+	// KeepPostHistory stores the previous version of a post whenever a
+	// fetch finds it edited, for the post-history command.
+	KeepPostHistory bool `json:"keep_post_history,omitempty"`
+
+	Fetch FetchConfig `json:"fetch,omitzero"`
+}
+
+// FetchConfig tunes how feeds are downloaded. Zero values fall back to the
+// defaults below.
+type FetchConfig struct {
+	ConnectTimeout Duration `json:"connect_timeout,omitempty"`
+	ReadTimeout    Duration `json:"read_timeout,omitempty"`
+	Timeout        Duration `json:"timeout,omitempty"`
+	MaxBodyBytes   int64    `json:"max_body_bytes,omitempty"`
+	MaxRedirects   int      `json:"max_redirects,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
+	UserAgent      string   `json:"user_agent,omitempty"`
+
+	// HostRequestsPerSecond is the sustained request rate allowed per host;
+	// a negative value disables the limit.
+	HostRequestsPerSecond float64  `json:"host_requests_per_second,omitempty"`
+	HostBurst             int      `json:"host_burst,omitempty"`
+	HostMinDelay          Duration `json:"host_min_delay,omitempty"`
+}
+
+const (
+	DefaultHostRequestsPerSecond = 1.0
+	DefaultHostBurst             = 2
+	DefaultHostMinDelay          = 250 * time.Millisecond
+)
+
+// HostLimits returns the per-host politeness settings with defaults applied.
+func (f FetchConfig) HostLimits() (rate float64, burst int, minDelay time.Duration) {
+	rate, burst, minDelay = f.HostRequestsPerSecond, f.HostBurst, time.Duration(f.HostMinDelay)
+	if rate == 0 {
+		rate = DefaultHostRequestsPerSecond
+	}
+	if burst == 0 {
+		burst = DefaultHostBurst
+	}
+	if minDelay == 0 {
+		minDelay = DefaultHostMinDelay
+	}
+	return rate, burst, minDelay
+}
+
+// Duration is a time.Duration written as a string such as "1s" or "500ms"
+// in the config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
 func Write(cfg Config) error {
@@ -60,6 +141,20 @@ func Read() (Config, error) {
 	return cfg, nil
 }
 
+func (c *Config) FeedFailureLimit() int {
+	if c.MaxFeedFailures == 0 {
+		return DefaultMaxFeedFailures
+	}
+	return c.MaxFeedFailures
+}
+
+func (c *Config) DeadFeedAge() time.Duration {
+	if c.DeadFeedAfter == 0 {
+		return DefaultDeadFeedAfter
+	}
+	return time.Duration(c.DeadFeedAfter)
+}
+
 func (c *Config) SetUser(username string) error {
 	if username == "" {
 		return errors.New("username cannot be empty")
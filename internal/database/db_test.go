@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// openTestDB migrates a throwaway schema in the database named by
+// GATOR_TEST_DB_URL. Tests that need Postgres are skipped without it.
+func openTestDB(t *testing.T) (*sql.DB, *Queries) {
+	t.Helper()
+
+	dbURL := os.Getenv("GATOR_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("GATOR_TEST_DB_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("could not open the database: %v", err)
+	}
+	// A single connection keeps the search_path set below for every query.
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("gator_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("could not create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		db.Close()
+	})
+	if _, err := db.Exec("SET search_path TO " + schema); err != nil {
+		t.Fatalf("could not set search_path: %v", err)
+	}
+
+	files, err := filepath.Glob("../../sql/schema/*.sql")
+	if err != nil {
+		t.Fatalf("could not list migrations: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("could not read %s: %v", file, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("could not apply %s: %v", filepath.Base(file), err)
+		}
+	}
+
+	return db, New(db)
+}
+
+func createTestFeed(t *testing.T, q *Queries, user User, url string) Feed {
+	t.Helper()
+
+	feed, err := q.CreateFeed(context.Background(), CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      url,
+		Url:       url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatalf("could not create feed: %v", err)
+	}
+	return feed
+}
+
+func TestClaimFeedsToFetchAfterManyFailures(t *testing.T) {
+	db, q := openTestDB(t)
+	ctx := context.Background()
+
+	user, err := q.CreateUser(ctx, CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      "test",
+	})
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	failing := createTestFeed(t, q, user, "https://failing.example.com/feed")
+	healthy := createTestFeed(t, q, user, "https://healthy.example.com/feed")
+
+	// Enough failures that an unclamped back-off overflows an interval.
+	if _, err := db.Exec(
+		"UPDATE feeds SET consecutive_failures = 100, last_error_at = NOW() WHERE id = $1",
+		failing.ID,
+	); err != nil {
+		t.Fatalf("could not record failures: %v", err)
+	}
+
+	claimed, err := q.ClaimFeedsToFetch(ctx, ClaimFeedsToFetchParams{
+		LeaseSeconds:  60,
+		FetchedBefore: time.Now(),
+		MaxFeeds:      10,
+	})
+	if err != nil {
+		t.Fatalf("ClaimFeedsToFetch() error: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != healthy.ID {
+		t.Errorf("claimed %d feeds, want only the healthy one", len(claimed))
+	}
+}
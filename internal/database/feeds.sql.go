@@ -7,11 +7,147 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feeds
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NOW() + make_interval(secs => $1::float8),
+    updated_at       = NOW()
+WHERE id = $2
+  AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
+`
+
+type ClaimFeedParams struct {
+	LeaseSeconds float64
+	ID           uuid.UUID
+}
+
+// Leases one feed for a manual fetch. Returns no row while another
+// aggregator holds the lease.
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.LeaseSeconds, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DeclaredIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RateLimitedUntil,
+		&i.Status,
+		&i.FailingSince,
+		&i.ImageUrl,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.Generator,
+		&i.IconUrl,
+	)
+	return i, err
+}
+
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NOW() + make_interval(secs => $1::float8),
+    updated_at       = NOW()
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+      AND (last_fetched_at IS NULL OR last_fetched_at < $2::timestamptz)
+      AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+      AND status = 'active'
+      AND (rate_limited_until IS NULL OR rate_limited_until <= NOW())
+      -- Back off exponentially after failures: 1m, 2m, 4m, ... capped at a day.
+      -- The exponent is clamped too, or the interval overflows after ~40
+      -- failures and the whole claim query errors out.
+      AND (
+        consecutive_failures = 0
+        OR last_error_at + LEAST(INTERVAL '1 minute' * POWER(2, LEAST(consecutive_failures - 1, 11)), INTERVAL '1 day') <= NOW()
+      )
+    ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST, created_at ASC
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
+`
+
+type ClaimFeedsToFetchParams struct {
+	LeaseSeconds  float64
+	FetchedBefore time.Time
+	MaxFeeds      int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.LeaseSeconds, arg.FetchedBefore, arg.MaxFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LeaseExpiresAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.NextFetchAt,
+			&i.DeclaredIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.RateLimitedUntil,
+			&i.Status,
+			&i.FailingSince,
+			&i.ImageUrl,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.Generator,
+			&i.IconUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -22,7 +158,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
 `
 
 type CreateFeedParams struct {
@@ -52,6 +188,25 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DeclaredIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RateLimitedUntil,
+		&i.Status,
+		&i.FailingSince,
+		&i.ImageUrl,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.Generator,
+		&i.IconUrl,
 	)
 	return i, err
 }
@@ -114,6 +269,16 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const deleteFeedFollowRecord = `-- name: DeleteFeedFollowRecord :exec
 DELETE FROM feed_follows
 WHERE user_id = $1
@@ -130,8 +295,83 @@ func (q *Queries) DeleteFeedFollowRecord(ctx context.Context, arg DeleteFeedFoll
 	return err
 }
 
+const enableFeed = `-- name: EnableFeed :execrows
+UPDATE feeds
+SET status               = 'active',
+    consecutive_failures = 0,
+    failing_since        = NULL,
+    updated_at           = NOW()
+WHERE url = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFeed, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBrokenFeeds = `-- name: GetBrokenFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
+FROM feeds
+WHERE status <> 'active'
+   OR consecutive_failures > 0
+ORDER BY status = 'active', consecutive_failures DESC, last_error_at DESC
+`
+
+func (q *Queries) GetBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LeaseExpiresAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.NextFetchAt,
+			&i.DeclaredIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.RateLimitedUntil,
+			&i.Status,
+			&i.FailingSince,
+			&i.ImageUrl,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.Generator,
+			&i.IconUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeed = `-- name: GetFeed :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
 FROM feeds
 ORDER BY created_at ASC
 `
@@ -153,6 +393,25 @@ func (q *Queries) GetFeed(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LeaseExpiresAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.NextFetchAt,
+			&i.DeclaredIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.RateLimitedUntil,
+			&i.Status,
+			&i.FailingSince,
+			&i.ImageUrl,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.Generator,
+			&i.IconUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -168,23 +427,14 @@ func (q *Queries) GetFeed(ctx context.Context) ([]Feed, error) {
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
 FROM feeds
 WHERE url = $1
 `
 
-type GetFeedByURLRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
-}
-
-func (q *Queries) GetFeedByURL(ctx context.Context, url string) (GetFeedByURLRow, error) {
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
-	var i GetFeedByURLRow
+	var i Feed
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
@@ -192,6 +442,26 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (GetFeedByURLRow
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DeclaredIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RateLimitedUntil,
+		&i.Status,
+		&i.FailingSince,
+		&i.ImageUrl,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.Generator,
+		&i.IconUrl,
 	)
 	return i, err
 }
@@ -204,7 +474,8 @@ SELECT
     ff.user_id,
     ff.feed_id,
     u.name AS user_name,
-    f.name AS feed_name
+    f.name AS feed_name,
+    f.status AS feed_status
 FROM feed_follows AS ff
 JOIN users AS u ON u.id = ff.user_id
 JOIN feeds AS f ON f.id = ff.feed_id
@@ -213,13 +484,14 @@ ORDER BY ff.created_at ASC
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	UserName   string
+	FeedName   string
+	FeedStatus string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -239,6 +511,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.FeedID,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -253,15 +526,58 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, created_at ASC
-LIMIT 1
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), ff.created_at, NOW(), ff.user_id, $1::uuid
+FROM feed_follows AS ff
+WHERE ff.feed_id = $2
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_error           = $1,
+    last_error_at        = NOW(),
+    consecutive_failures = consecutive_failures + 1,
+    failing_since        = COALESCE(failing_since, NOW()),
+    status               = CASE
+        WHEN status = 'dead' OR $2::bool THEN 'dead'
+        WHEN $3::float8 > 0
+             AND failing_since < NOW() - make_interval(secs => $3::float8) THEN 'dead'
+        WHEN $4::int > 0 AND consecutive_failures + 1 >= $4::int THEN 'paused'
+        ELSE status
+    END,
+    updated_at           = NOW()
+WHERE id = $5
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, lease_expires_at, last_error, last_error_at, consecutive_failures, next_fetch_at, declared_interval_seconds, skip_hours, skip_days, rate_limited_until, status, failing_since, image_url, site_url, description, language, generator, icon_url
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type RecordFeedFailureParams struct {
+	LastError        sql.NullString
+	Gone             bool
+	DeadAfterSeconds float64
+	MaxFailures      int32
+	ID               uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.Gone,
+		arg.DeadAfterSeconds,
+		arg.MaxFailures,
+		arg.ID,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -271,18 +587,165 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LeaseExpiresAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.DeclaredIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RateLimitedUntil,
+		&i.Status,
+		&i.FailingSince,
+		&i.ImageUrl,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.Generator,
+		&i.IconUrl,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
 UPDATE feeds
-SET last_fetched_at = NOW(),
-    updated_at      = NOW()
+SET consecutive_failures = 0,
+    failing_since        = NULL,
+    updated_at           = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReleaseFeedLease(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, id)
+	return err
+}
+
+const setFeedRateLimited = `-- name: SetFeedRateLimited :exec
+UPDATE feeds
+SET rate_limited_until = $2,
+    updated_at         = NOW()
+WHERE id = $1
+`
+
+type SetFeedRateLimitedParams struct {
+	ID               uuid.UUID
+	RateLimitedUntil sql.NullTime
+}
+
+func (q *Queries) SetFeedRateLimited(ctx context.Context, arg SetFeedRateLimitedParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRateLimited, arg.ID, arg.RateLimitedUntil)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_url    = $2,
+    description = $3,
+    language    = $4,
+    generator   = $5,
+    icon_url    = $6,
+    image_url   = $7,
+    updated_at  = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	SiteUrl     sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	Generator   sql.NullString
+	IconUrl     sql.NullString
+	ImageUrl    sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.SiteUrl,
+		arg.Description,
+		arg.Language,
+		arg.Generator,
+		arg.IconUrl,
+		arg.ImageUrl,
+	)
+	return err
+}
+
+const updateFeedSchedule = `-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET next_fetch_at             = $2,
+    declared_interval_seconds = $3,
+    skip_hours                = $4,
+    skip_days                 = $5
+WHERE id = $1
+`
+
+type UpdateFeedScheduleParams struct {
+	ID                      uuid.UUID
+	NextFetchAt             sql.NullTime
+	DeclaredIntervalSeconds sql.NullInt32
+	SkipHours               []int32
+	SkipDays                []string
+}
+
+func (q *Queries) UpdateFeedSchedule(ctx context.Context, arg UpdateFeedScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedSchedule,
+		arg.ID,
+		arg.NextFetchAt,
+		arg.DeclaredIntervalSeconds,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url        = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
+
+const updateFeedValidators = `-- name: UpdateFeedValidators :exec
+UPDATE feeds
+SET etag          = $2,
+    last_modified = $3,
+    updated_at    = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedValidators(ctx context.Context, arg UpdateFeedValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedValidators, arg.ID, arg.Etag, arg.LastModified)
 	return err
 }
@@ -11,14 +11,55 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID              uuid.UUID
+	PostID          uuid.UUID
+	CreatedAt       time.Time
+	Url             string
+	MimeType        sql.NullString
+	LengthBytes     sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	Name                    string
+	Url                     string
+	UserID                  uuid.UUID
+	LastFetchedAt           sql.NullTime
+	Etag                    sql.NullString
+	LastModified            sql.NullString
+	LeaseExpiresAt          sql.NullTime
+	LastError               sql.NullString
+	LastErrorAt             sql.NullTime
+	ConsecutiveFailures     int32
+	NextFetchAt             sql.NullTime
+	DeclaredIntervalSeconds sql.NullInt32
+	SkipHours               []int32
+	SkipDays                []string
+	RateLimitedUntil        sql.NullTime
+	Status                  string
+	FailingSince            sql.NullTime
+	ImageUrl                sql.NullString
+	SiteUrl                 sql.NullString
+	Description             sql.NullString
+	Language                sql.NullString
+	Generator               sql.NullString
+	IconUrl                 sql.NullString
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	StartedAt  time.Time
+	DurationMs int32
+	StatusCode sql.NullInt32
+	NewPosts   int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -38,6 +79,22 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        sql.NullString
+	Author      sql.NullString
+	Categories  []string
+	Content     sql.NullString
+	ImageUrl    sql.NullString
+}
+
+type PostRevision struct {
+	ID          uuid.UUID
+	PostID      uuid.UUID
+	UpdatedAt   time.Time
+	ReplacedAt  time.Time
+	Title       string
+	Description sql.NullString
+	Content     sql.NullString
+	PublishedAt sql.NullTime
 }
 
 type User struct {
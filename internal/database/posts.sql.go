@@ -11,72 +11,85 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (
-    id,
-    created_at,
-    updated_at,
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
-)
-VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-)
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, author, categories, content, image_url
+FROM posts
+WHERE url = $1
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-}
-
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Content,
+		&i.ImageUrl,
 	)
-	return err
+	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.guid, p.author, p.categories, p.content, p.image_url, f.name AS feed_name, f.image_url AS feed_image_url
 FROM posts p
 JOIN feed_follows ff ON ff.feed_id = p.feed_id
+JOIN feeds f ON f.id = p.feed_id
 WHERE ff.user_id = $1
+  AND ($2::text IS NULL
+       OR EXISTS (
+           SELECT 1
+           FROM unnest(p.categories) AS c
+           WHERE lower(c) = lower($2)
+       ))
 ORDER BY p.published_at DESC NULLS LAST, p.created_at DESC
-LIMIT $2
+LIMIT $3
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Category sql.NullString
+	MaxPosts int32
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+type GetPostsForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Guid         sql.NullString
+	Author       sql.NullString
+	Categories   []string
+	Content      sql.NullString
+	ImageUrl     sql.NullString
+	FeedName     string
+	FeedImageUrl sql.NullString
+}
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Category, arg.MaxPosts)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -86,6 +99,13 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Content,
+			&i.ImageUrl,
+			&i.FeedName,
+			&i.FeedImageUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -99,3 +119,138 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getRecentPublishTimes = `-- name: GetRecentPublishTimes :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+  AND published_at IS NOT NULL
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPublishTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPublishTimes(ctx context.Context, arg GetRecentPublishTimesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPublishTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    guid,
+    author,
+    categories,
+    content,
+    image_url
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+ON CONFLICT (url) DO UPDATE
+SET title        = EXCLUDED.title,
+    description  = EXCLUDED.description,
+    published_at = COALESCE(EXCLUDED.published_at, posts.published_at),
+    guid         = EXCLUDED.guid,
+    author       = EXCLUDED.author,
+    categories   = EXCLUDED.categories,
+    content      = EXCLUDED.content,
+    image_url    = EXCLUDED.image_url,
+    updated_at   = EXCLUDED.updated_at
+WHERE posts.feed_id = EXCLUDED.feed_id
+  AND (posts.title, posts.description, posts.published_at, posts.guid,
+       posts.author, posts.categories, posts.content, posts.image_url)
+      IS DISTINCT FROM
+      (EXCLUDED.title, EXCLUDED.description, COALESCE(EXCLUDED.published_at, posts.published_at), EXCLUDED.guid,
+       EXCLUDED.author, EXCLUDED.categories, EXCLUDED.content, EXCLUDED.image_url)
+RETURNING id, (xmax = 0) AS inserted
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        sql.NullString
+	Author      sql.NullString
+	Categories  []string
+	Content     sql.NullString
+	ImageUrl    sql.NullString
+}
+
+type UpsertPostRow struct {
+	ID       uuid.UUID
+	Inserted bool
+}
+
+// Only posts of the same feed are updated, and only when something
+// changed; otherwise no row is returned.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Author,
+		pq.Array(arg.Categories),
+		arg.Content,
+		arg.ImageUrl,
+	)
+	var i UpsertPostRow
+	err := row.Scan(&i.ID, &i.Inserted)
+	return i, err
+}
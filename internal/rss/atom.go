@@ -0,0 +1,169 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	Base      string        `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Lang      string        `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Logo      string        `xml:"logo"`
+	Icon      string        `xml:"icon"`
+	Generator atomGenerator `xml:"generator"`
+	Title     atomText      `xml:"title"`
+	Subtitle  atomText      `xml:"subtitle"`
+	Links     []atomLink    `xml:"link"`
+	Entries   []atomEntry   `xml:"entry"`
+}
+
+type atomGenerator struct {
+	Name string `xml:",chardata"`
+	URI  string `xml:"uri,attr"`
+}
+
+type atomEntry struct {
+	// YouTube and others put Media RSS in Atom entries.
+	MediaRSS
+
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// atomText keeps both the decoded text and the raw markup, since
+// type="xhtml" constructs carry their content as child elements.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// xhtmlDiv matches the <div> that wraps type="xhtml" content, which RFC
+// 4287 says is not part of the content.
+var xhtmlDiv = regexp.MustCompile(`(?s)^<(?:[\w.-]+:)?div\b[^>]*>(.*)</(?:[\w.-]+:)?div\s*>$`)
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		inner := strings.TrimSpace(t.Inner)
+		if m := xhtmlDiv.FindStringSubmatch(inner); m != nil {
+			inner = m[1]
+		}
+		return strings.TrimSpace(inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+func parseAtom(data []byte) (*RRSFeed, error) {
+	af, skipped, err := decodeFeed(data, "entry", func(f *atomFeed) *[]atomEntry {
+		return &f.Entries
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the Atom XML: %v", err)
+	}
+
+	feed := RRSFeed{SkippedItems: skipped}
+	feed.Channel.Title = af.Title.String()
+	feed.Channel.Link = alternateLink(af.Links)
+	feed.Channel.Description = af.Subtitle.String()
+	feed.Channel.Image.URL = strings.TrimSpace(af.Logo)
+	feed.Channel.Icon = strings.TrimSpace(af.Icon)
+	feed.Channel.Base = strings.TrimSpace(af.Base)
+	feed.Channel.Language = strings.TrimSpace(af.Lang)
+	feed.Channel.Generator = strings.TrimSpace(af.Generator.Name)
+	if feed.Channel.Generator == "" {
+		feed.Channel.Generator = strings.TrimSpace(af.Generator.URI)
+	}
+
+	for _, e := range af.Entries {
+		description := e.Summary.String()
+		if description == "" {
+			description = e.Content.String()
+		}
+
+		pubDate := strings.TrimSpace(e.Published)
+		if pubDate == "" {
+			pubDate = strings.TrimSpace(e.Updated)
+		}
+
+		link := alternateLink(e.Links)
+		if link == "" && strings.HasPrefix(e.ID, "http") {
+			link = strings.TrimSpace(e.ID)
+		}
+
+		var authors []string
+		for _, a := range e.Authors {
+			if name := strings.TrimSpace(a.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+
+		item := RSSItem{
+			Title:       e.Title.String(),
+			Link:        link,
+			Description: description,
+			PubDate:     pubDate,
+			GUID:        e.ID,
+			Author:      strings.Join(authors, ", "),
+			Content:     e.Content.String(),
+			MediaRSS:    e.MediaRSS,
+			Base:        strings.TrimSpace(e.Base),
+		}
+		for _, c := range e.Categories {
+			item.Categories = append(item.Categories, c.Term)
+		}
+		for _, l := range e.Links {
+			if l.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, Enclosure{URL: l.Href, Type: l.Type, Length: l.Length})
+			}
+		}
+		item.normalize()
+
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return &feed, nil
+}
+
+// alternateLink picks the rel="alternate" link (the default when rel is
+// omitted), preferring an HTML one when several are present.
+func alternateLink(links []atomLink) string {
+	var fallback string
+	for _, l := range links {
+		if l.Rel != "" && l.Rel != "alternate" {
+			continue
+		}
+		if l.Type == "" || l.Type == "text/html" {
+			return strings.TrimSpace(l.Href)
+		}
+		if fallback == "" {
+			fallback = strings.TrimSpace(l.Href)
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,53 @@
+package rss
+
+import "testing"
+
+func TestParseAtomText(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+		want    string
+	}{
+		{
+			name:    "text",
+			summary: `<summary>Tom &amp; Jerry</summary>`,
+			want:    "Tom & Jerry",
+		},
+		{
+			name:    "html is decoded once",
+			summary: `<summary type="html">&lt;p&gt;a &amp;lt;tag&amp;gt;&lt;/p&gt;</summary>`,
+			want:    "<p>a &lt;tag&gt;</p>",
+		},
+		{
+			name:    "xhtml wrapper div is dropped",
+			summary: `<summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"> <p>Hi <b>there</b></p> </div></summary>`,
+			want:    "<p>Hi <b>there</b></p>",
+		},
+		{
+			name:    "prefixed xhtml wrapper div is dropped",
+			summary: `<summary type="xhtml"><xhtml:div xmlns:xhtml="http://www.w3.org/1999/xhtml"><xhtml:p>Hi</xhtml:p></xhtml:div></summary>`,
+			want:    "<xhtml:p>Hi</xhtml:p>",
+		},
+		{
+			name:    "xhtml without a wrapper is kept",
+			summary: `<summary type="xhtml"><p>one</p><p>two</p></summary>`,
+			want:    "<p>one</p><p>two</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title><entry><title>a</title>` + tt.summary + `</entry></feed>`
+			feed, err := parseFeed([]byte(data), "")
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+			}
+			if got := feed.Channel.Items[0].Description; got != tt.want {
+				t.Errorf("description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// xmlDeclEncoding matches the encoding pseudo-attribute of a leading XML
+// declaration. The declaration is plain ASCII in every charset we accept.
+var xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([^"']*)["']`)
+
+// toUTF8 re-encodes a feed body as UTF-8. The charset comes from the
+// Content-Type header if it names one, otherwise from the XML declaration.
+// Bodies that claim UTF-8 but are not valid UTF-8 are treated as
+// windows-1252, which is what such feeds almost always turn out to be.
+func toUTF8(data []byte, contentType string) ([]byte, error) {
+	if bytes.HasPrefix(data, utf8BOM) {
+		return setDeclaredEncoding(data[len(utf8BOM):]), nil
+	}
+
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if label == "" {
+		if m := xmlDeclEncoding.FindSubmatch(data); m != nil {
+			label = string(m[1])
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		if utf8.Valid(data) {
+			return setDeclaredEncoding(data), nil
+		}
+	case "iso-8859-1", "iso8859-1", "latin1", "l1", "windows-1252", "cp1252", "x-cp1252":
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", label)
+	}
+
+	return setDeclaredEncoding(decodeWindows1252(data)), nil
+}
+
+// setDeclaredEncoding rewrites the XML declaration to say UTF-8, since
+// encoding/xml refuses any other declared encoding.
+func setDeclaredEncoding(data []byte) []byte {
+	m := xmlDeclEncoding.FindSubmatchIndex(data)
+	if m == nil || strings.EqualFold(string(data[m[2]:m[3]]), "utf-8") {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:m[2]]...)
+	out = append(out, "UTF-8"...)
+	return append(out, data[m[3]:]...)
+}
+
+// windows1252 maps bytes 0x80-0x9F, where windows-1252 differs from
+// ISO-8859-1. Unassigned bytes keep their ISO-8859-1 control code.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 decodes data as windows-1252. Like browsers, it is also
+// used for ISO-8859-1, which only differs in the 0x80-0x9F control range
+// that real feeds use for curly quotes and dashes.
+func decodeWindows1252(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/4)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			out = append(out, b)
+		case b < 0xA0:
+			out = utf8.AppendRune(out, windows1252[b-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(b))
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package rss
+
+import "testing"
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name: "utf-8 is kept",
+			data: "<t>café</t>",
+			want: "<t>café</t>",
+		},
+		{
+			name:        "latin1 from the content type",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			data:        "<t>caf\xe9</t>",
+			want:        "<t>café</t>",
+		},
+		{
+			name: "latin1 from the xml declaration",
+			data: "<?xml version=\"1.0\" encoding=\"iso-8859-1\"?><t>na\xefve</t>",
+			want: `<?xml version="1.0" encoding="UTF-8"?><t>naïve</t>`,
+		},
+		{
+			name: "cp1252 punctuation",
+			data: "<?xml version='1.0' encoding='windows-1252'?><t>\x93quoted\x94 \x96 \x805</t>",
+			want: `<?xml version='1.0' encoding='UTF-8'?><t>“quoted” – €5</t>`,
+		},
+		{
+			name:        "latin1 label decodes cp1252 punctuation too",
+			contentType: "text/xml; charset=latin1",
+			data:        "<t>it\x92s</t>",
+			want:        "<t>it’s</t>",
+		},
+		{
+			name:        "content type wins over the declaration",
+			contentType: "text/xml; charset=windows-1252",
+			data:        "<?xml version=\"1.0\" encoding=\"utf-8\"?><t>\xe9</t>",
+			want:        `<?xml version="1.0" encoding="utf-8"?><t>é</t>`,
+		},
+		{
+			name: "invalid utf-8 is read as cp1252",
+			data: "<?xml version=\"1.0\" encoding=\"UTF-8\"?><t>\x93hi\x94</t>",
+			want: `<?xml version="1.0" encoding="UTF-8"?><t>“hi”</t>`,
+		},
+		{
+			name: "byte order mark is stripped",
+			data: "\xef\xbb\xbf<t>ok</t>",
+			want: "<t>ok</t>",
+		},
+		{
+			name:        "unsupported charset",
+			contentType: "text/xml; charset=Shift_JIS",
+			data:        "<t>x</t>",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8([]byte(tt.data), tt.contentType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("toUTF8() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toUTF8() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("toUTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLatin1Feed(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` +
+		"<rss version=\"2.0\"><channel><title>Caf\xe9</title>" +
+		"<item><title>\x93Cr\xe8me br\xfbl\xe9e\x94</title><link>https://example.com/a</link></item>" +
+		"</channel></rss>")
+
+	utf8Data, err := toUTF8(data, "")
+	if err != nil {
+		t.Fatalf("toUTF8() error: %v", err)
+	}
+	feed, err := parseFeed(utf8Data, "")
+	if err != nil {
+		t.Fatalf("parseFeed() error: %v", err)
+	}
+
+	if got, want := feed.Channel.Title, "Café"; got != want {
+		t.Errorf("channel title = %q, want %q", got, want)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+	}
+	if got, want := feed.Channel.Items[0].Title, "“Crème brûlée”"; got != want {
+		t.Errorf("item title = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+package rss
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 30 * time.Second
+	DefaultTimeout        = 2 * time.Minute
+	DefaultMaxBodySize    = 10 << 20
+	DefaultMaxRedirects   = 5
+	DefaultUserAgent      = "gator"
+)
+
+// Options configure a Fetcher. Zero values fall back to the defaults above.
+type Options struct {
+	// ConnectTimeout bounds dialing and the TLS handshake.
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for response headers and for each chunk
+	// of the body, so a server that stalls mid-response is abandoned.
+	ReadTimeout time.Duration
+	// Timeout bounds the whole fetch, including any wait for the Limiter,
+	// so a server trickling bytes just fast enough to beat ReadTimeout
+	// still gives up eventually.
+	Timeout time.Duration
+	// MaxBodySize caps the decompressed response body in bytes.
+	MaxBodySize int64
+	// MaxRedirects is how many redirects are followed; negative disables
+	// following redirects.
+	MaxRedirects int
+	// Proxy overrides the HTTP(S)_PROXY environment variables when set.
+	Proxy     *url.URL
+	UserAgent string
+	// Limiter, when set, spaces out requests to the same host.
+	Limiter *HostLimiter
+}
+
+// Fetcher downloads and parses feeds. It is safe for concurrent use.
+type Fetcher struct {
+	client *http.Client
+	opts   Options
+}
+
+var defaultFetcher = NewFetcher(Options{})
+
+func NewFetcher(opts Options) *Fetcher {
+	if opts.ConnectTimeout == 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	if opts.ReadTimeout == 0 {
+		opts.ReadTimeout = DefaultReadTimeout
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.MaxRedirects == 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != nil {
+		proxy = http.ProxyURL(opts.Proxy)
+	}
+
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.ReadTimeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   2,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if opts.MaxRedirects < 0 {
+				return errors.New("redirects are disabled")
+			}
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+			}
+			return nil
+		},
+	}
+
+	return &Fetcher{client: client, opts: opts}
+}
+
+var (
+	errReadTimeout  = errors.New("timed out reading the response")
+	errFetchTimeout = errors.New("timed out fetching the feed")
+)
+
+func (f *Fetcher) Fetch(ctx context.Context, feedURL string, cached Validators) (*FetchResult, error) {
+	ctx, cancelTimeout := context.WithTimeoutCause(ctx, f.opts.Timeout, errFetchTimeout)
+	defer cancelTimeout()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the request: %v", err)
+	}
+
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	if f.opts.Limiter != nil {
+		if err := f.opts.Limiter.Wait(ctx, req.URL.Host); err != nil {
+			return nil, timeoutCause(ctx, err)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error doing the request: %v", timeoutCause(ctx, err))
+	}
+	defer resp.Body.Close()
+
+	validators := Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	finalURL, redirected, permanent := redirectChain(resp)
+
+	if resp.StatusCode == http.StatusNotModified {
+		// A 304 may omit the validators; keep the ones we already have.
+		if validators.ETag == "" {
+			validators.ETag = cached.ETag
+		}
+		if validators.LastModified == "" {
+			validators.LastModified = cached.LastModified
+		}
+		return &FetchResult{
+			StatusCode:        resp.StatusCode,
+			NotModified:       true,
+			Validators:        validators,
+			FinalURL:          finalURL,
+			Redirected:        redirected,
+			PermanentRedirect: permanent,
+		}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			statusErr.RetryAfter = parseRetryAfter(resp.Header, time.Now())
+			if statusErr.RetryAfter == 0 && resp.StatusCode == http.StatusTooManyRequests {
+				statusErr.RetryAfter = defaultRetryAfter
+			}
+			if f.opts.Limiter != nil && statusErr.RetryAfter > 0 {
+				f.opts.Limiter.Block(req.URL.Host, time.Now().Add(statusErr.RetryAfter))
+			}
+		}
+
+		return nil, statusErr
+	}
+
+	data, err := f.readBody(resp, cancel)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the response: %v", timeoutCause(ctx, err))
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+
+	data, err = toUTF8(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding the response: %v", err)
+	}
+
+	feed, err := parseFeed(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	feed.resolveURLs(finalURL)
+
+	return &FetchResult{
+		Feed:              feed,
+		StatusCode:        resp.StatusCode,
+		Validators:        validators,
+		FinalURL:          finalURL,
+		Redirected:        redirected,
+		PermanentRedirect: permanent,
+	}, nil
+}
+
+// redirectChain walks back through the redirects that led to resp and
+// reports the final URL, whether there were any redirects, and whether all
+// of them were permanent.
+func redirectChain(resp *http.Response) (finalURL string, redirected, permanent bool) {
+	finalURL = resp.Request.URL.String()
+	permanent = true
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		redirected = true
+		if r.StatusCode != http.StatusMovedPermanently && r.StatusCode != http.StatusPermanentRedirect {
+			permanent = false
+		}
+	}
+	return finalURL, redirected, redirected && permanent
+}
+
+// timeoutCause replaces err with the reason ctx was cancelled when one of
+// our own timeouts fired, which says more than "context canceled".
+func timeoutCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errReadTimeout) || errors.Is(cause, errFetchTimeout) {
+		return cause
+	}
+	return err
+}
+
+// readBody decodes the Content-Encoding and reads at most MaxBodySize
+// bytes, cancelling the request if the server goes quiet for longer than
+// ReadTimeout.
+func (f *Fetcher) readBody(resp *http.Response, cancel context.CancelCauseFunc) ([]byte, error) {
+	var body io.Reader = &idleTimeoutReader{
+		r:       resp.Body,
+		timeout: f.opts.ReadTimeout,
+		cancel:  cancel,
+	}
+
+	switch enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	case "deflate":
+		fl, err := newDeflateReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer fl.Close()
+		body = fl
+	case "br":
+		body = brotli.NewReader(body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", enc)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, f.opts.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.opts.MaxBodySize {
+		return nil, fmt.Errorf("response larger than %d bytes", f.opts.MaxBodySize)
+	}
+	return data, nil
+}
+
+// newDeflateReader handles "deflate" bodies, which should be zlib-wrapped
+// but are raw DEFLATE on some servers.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// idleTimeoutReader cancels the request when no data arrives for timeout.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+
+	once  sync.Once
+	timer *time.Timer
+}
+
+func (t *idleTimeoutReader) Read(b []byte) (int, error) {
+	t.once.Do(func() {
+		t.timer = time.AfterFunc(t.timeout, func() { t.cancel(errReadTimeout) })
+	})
+	t.timer.Reset(t.timeout)
+	n, err := t.r.Read(b)
+	if err != nil {
+		t.timer.Stop()
+	}
+	return n, err
+}
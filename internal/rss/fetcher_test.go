@@ -0,0 +1,312 @@
+package rss
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const fetchDoc = `<rss version="2.0"><channel><title>fetched</title></channel></rss>`
+
+// compress encodes data with w, which wraps the buffer it is given.
+func compress(t *testing.T, data string, w func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := w(&buf)
+	if _, err := io.WriteString(zw, data); err != nil {
+		t.Fatalf("could not compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("could not compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetcherDecodesBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantErr  string
+	}{
+		{name: "identity", body: []byte(fetchDoc)},
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			body:     compress(t, fetchDoc, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+		},
+		{
+			name:     "zlib deflate",
+			encoding: "deflate",
+			body:     compress(t, fetchDoc, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+		},
+		{
+			name:     "raw deflate",
+			encoding: "deflate",
+			body: compress(t, fetchDoc, func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			}),
+		},
+		{
+			name:     "brotli",
+			encoding: "br",
+			body:     compress(t, fetchDoc, func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }),
+		},
+		{
+			name:     "unsupported encoding",
+			encoding: "zstd",
+			body:     []byte(fetchDoc),
+			wantErr:  `unsupported content encoding "zstd"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			res, err := NewFetcher(Options{}).Fetch(context.Background(), srv.URL, Validators{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error: %v", err)
+			}
+			if res.Feed.Channel.Title != "fetched" {
+				t.Errorf("title = %q, want %q", res.Feed.Channel.Title, "fetched")
+			}
+		})
+	}
+}
+
+func TestFetcherMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBodySize int64
+		encoding    string
+		body        []byte
+		wantErr     bool
+	}{
+		{name: "exactly at the limit", maxBodySize: int64(len(fetchDoc)), body: []byte(fetchDoc)},
+		{name: "over the limit", maxBodySize: int64(len(fetchDoc)) - 1, body: []byte(fetchDoc), wantErr: true},
+		{
+			name:        "limit applies after decompression",
+			maxBodySize: 1 << 10,
+			encoding:    "gzip",
+			body: compress(t, strings.Repeat(" ", 1<<20)+fetchDoc, func(w io.Writer) io.WriteCloser {
+				return gzip.NewWriter(w)
+			}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := NewFetcher(Options{MaxBodySize: tt.maxBodySize}).Fetch(context.Background(), srv.URL, Validators{})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "response larger than") {
+					t.Fatalf("Fetch() error = %v, want the body size error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFetcherNotModified(t *testing.T) {
+	tests := []struct {
+		name         string
+		etag         string
+		lastModified string
+		want         Validators
+	}{
+		{
+			name: "validators are kept when the 304 omits them",
+			want: Validators{ETag: `"v1"`, LastModified: "Mon, 06 May 2024 10:00:00 GMT"},
+		},
+		{
+			name: "new validators replace the cached ones",
+			etag: `"v2"`,
+			want: Validators{ETag: `"v2"`, LastModified: "Mon, 06 May 2024 10:00:00 GMT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached := Validators{ETag: `"v1"`, LastModified: "Mon, 06 May 2024 10:00:00 GMT"}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") != cached.ETag || r.Header.Get("If-Modified-Since") != cached.LastModified {
+					t.Errorf("conditional headers = %q, %q", r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since"))
+				}
+				if tt.etag != "" {
+					w.Header().Set("ETag", tt.etag)
+				}
+				w.WriteHeader(http.StatusNotModified)
+			}))
+			defer srv.Close()
+
+			res, err := NewFetcher(Options{}).Fetch(context.Background(), srv.URL, cached)
+			if err != nil {
+				t.Fatalf("Fetch() error: %v", err)
+			}
+			if !res.NotModified || res.Feed != nil {
+				t.Errorf("NotModified = %v with feed %v, want a 304 without a feed", res.NotModified, res.Feed)
+			}
+			if res.Validators != tt.want {
+				t.Errorf("Validators = %+v, want %+v", res.Validators, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetcherRedirectLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRedirects int
+		hops         int
+		wantErr      string
+	}{
+		{name: "within the limit", maxRedirects: 2, hops: 2},
+		{name: "too many redirects", maxRedirects: 1, hops: 2, wantErr: "stopped after 1 redirects"},
+		{name: "redirects disabled", maxRedirects: -1, hops: 1, wantErr: "redirects are disabled"},
+		{name: "no redirect with redirects disabled", maxRedirects: -1, hops: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var hop int
+				if _, err := fmt.Sscanf(r.URL.Path, "/hop/%d", &hop); err == nil && hop < tt.hops {
+					http.Redirect(w, r, fmt.Sprintf("/hop/%d", hop+1), http.StatusFound)
+					return
+				}
+				if r.URL.Path == "/" && tt.hops > 0 {
+					http.Redirect(w, r, "/hop/1", http.StatusFound)
+					return
+				}
+				io.WriteString(w, fetchDoc)
+			}))
+			defer srv.Close()
+
+			f := NewFetcher(Options{MaxRedirects: tt.maxRedirects})
+			_, err := f.Fetch(context.Background(), srv.URL+"/", Validators{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFetcherTimeout(t *testing.T) {
+	// The server trickles a byte at a time, fast enough to beat the read
+	// timeout but never finishing.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<rss version="2.0"><channel>`)
+		for {
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+				io.WriteString(w, " ")
+			}
+		}
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(Options{ReadTimeout: time.Second, Timeout: 200 * time.Millisecond})
+	_, err := f.Fetch(context.Background(), srv.URL, Validators{})
+	if err == nil || !strings.Contains(err.Error(), errFetchTimeout.Error()) {
+		t.Fatalf("Fetch() error = %v, want %q", err, errFetchTimeout)
+	}
+}
+
+func TestFetcherRedirectChain(t *testing.T) {
+	tests := []struct {
+		name          string
+		codes         []int
+		wantPermanent bool
+	}{
+		{name: "no redirect"},
+		{name: "moved permanently", codes: []int{http.StatusMovedPermanently}, wantPermanent: true},
+		{name: "permanent redirect", codes: []int{http.StatusPermanentRedirect}, wantPermanent: true},
+		{name: "found", codes: []int{http.StatusFound}},
+		{name: "temporary redirect", codes: []int{http.StatusTemporaryRedirect}},
+		{
+			name:          "permanent chain",
+			codes:         []int{http.StatusMovedPermanently, http.StatusPermanentRedirect},
+			wantPermanent: true,
+		},
+		{name: "temporary hop in a permanent chain", codes: []int{http.StatusMovedPermanently, http.StatusFound}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var hop int
+				fmt.Sscanf(r.URL.Path, "/hop/%d", &hop)
+				if hop < len(tt.codes) {
+					http.Redirect(w, r, fmt.Sprintf("/hop/%d", hop+1), tt.codes[hop])
+					return
+				}
+				io.WriteString(w, fetchDoc)
+			}))
+			defer srv.Close()
+
+			res, err := NewFetcher(Options{}).Fetch(context.Background(), srv.URL+"/hop/0", Validators{})
+			if err != nil {
+				t.Fatalf("Fetch() error: %v", err)
+			}
+
+			wantURL := fmt.Sprintf("%s/hop/%d", srv.URL, len(tt.codes))
+			if res.FinalURL != wantURL {
+				t.Errorf("FinalURL = %q, want %q", res.FinalURL, wantURL)
+			}
+			if res.Redirected != (len(tt.codes) > 0) {
+				t.Errorf("Redirected = %v, want %v", res.Redirected, len(tt.codes) > 0)
+			}
+			if res.PermanentRedirect != tt.wantPermanent {
+				t.Errorf("PermanentRedirect = %v, want %v", res.PermanentRedirect, tt.wantPermanent)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeclaredInterval returns how often the publisher says the feed should be
+// polled, from <ttl> or sy:updatePeriod/sy:updateFrequency. It returns 0
+// when the feed declares nothing usable.
+func (f *RRSFeed) DeclaredInterval() time.Duration {
+	var interval time.Duration
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(f.Channel.TTL)); err == nil && ttl > 0 {
+		interval = time.Duration(ttl) * time.Minute
+	}
+
+	if period := syndicationPeriod(f.Channel.UpdatePeriod); period > 0 {
+		frequency, err := strconv.Atoi(strings.TrimSpace(f.Channel.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		// Take the longer of the two hints, so we never poll more often
+		// than either asks.
+		interval = max(interval, period/time.Duration(frequency))
+	}
+
+	return interval
+}
+
+// QuietHours returns the declared <skipHours> as GMT hours 0-23, ignoring
+// malformed entries. Some feeds write midnight as 24.
+func (f *RRSFeed) QuietHours() []int {
+	var hours []int
+	for _, raw := range f.Channel.SkipHours {
+		h, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || h < 0 || h > 24 {
+			continue
+		}
+		hours = append(hours, h%24)
+	}
+	return hours
+}
+
+// QuietDays returns the declared <skipDays>, ignoring unknown day names.
+func (f *RRSFeed) QuietDays() []time.Weekday {
+	var days []time.Weekday
+	for _, raw := range f.Channel.SkipDays {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(strings.TrimSpace(raw), d.String()) {
+				days = append(days, d)
+				break
+			}
+		}
+	}
+	return days
+}
+
+func syndicationPeriod(raw string) time.Duration {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "hourly":
+		return time.Hour
+	case "daily":
+		return 24 * time.Hour
+	case "weekly":
+		return 7 * 24 * time.Hour
+	case "monthly":
+		return 30 * 24 * time.Hour
+	case "yearly":
+		return 365 * 24 * time.Hour
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeclaredInterval(t *testing.T) {
+	tests := []struct {
+		name            string
+		ttl             string
+		updatePeriod    string
+		updateFrequency string
+		want            time.Duration
+	}{
+		{name: "nothing declared", want: 0},
+		{name: "ttl in minutes", ttl: " 90 ", want: 90 * time.Minute},
+		{name: "invalid ttl", ttl: "soon", want: 0},
+		{name: "negative ttl", ttl: "-5", want: 0},
+		{name: "update period", updatePeriod: "daily", want: 24 * time.Hour},
+		{name: "update frequency divides the period", updatePeriod: "Hourly", updateFrequency: "4", want: 15 * time.Minute},
+		{name: "invalid frequency counts as one", updatePeriod: "weekly", updateFrequency: "0", want: 7 * 24 * time.Hour},
+		{name: "unknown period", updatePeriod: "fortnightly", want: 0},
+		{name: "longer hint wins", ttl: "60", updatePeriod: "daily", updateFrequency: "2", want: 12 * time.Hour},
+		{name: "ttl longer than the period", ttl: "1440", updatePeriod: "hourly", want: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &RRSFeed{}
+			feed.Channel.TTL = tt.ttl
+			feed.Channel.UpdatePeriod = tt.updatePeriod
+			feed.Channel.UpdateFrequency = tt.updateFrequency
+
+			if got := feed.DeclaredInterval(); got != tt.want {
+				t.Errorf("DeclaredInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	feed := &RRSFeed{}
+	feed.Channel.SkipHours = []string{"0", " 5 ", "23", "24", "25", "-1", "noon"}
+
+	want := []int{0, 5, 23, 0}
+	if got := feed.QuietHours(); !slices.Equal(got, want) {
+		t.Errorf("QuietHours() = %v, want %v", got, want)
+	}
+}
+
+func TestQuietDays(t *testing.T) {
+	feed := &RRSFeed{}
+	feed.Channel.SkipDays = []string{"Saturday", " sunday ", "MONDAY", "Caturday", ""}
+
+	want := []time.Weekday{time.Saturday, time.Sunday, time.Monday}
+	if got := feed.QuietDays(); !slices.Equal(got, want) {
+		t.Errorf("QuietDays() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package rss
+
+import "testing"
+
+func TestParseJSONFeed(t *testing.T) {
+	tests := []struct {
+		name            string
+		item            string
+		wantTitle       string
+		wantGUID        string
+		wantDescription string
+	}{
+		{
+			name:            "string id",
+			item:            `{"id": "tag:example.com,2024:1", "title": "a", "content_text": "hello"}`,
+			wantTitle:       "a",
+			wantGUID:        "tag:example.com,2024:1",
+			wantDescription: "hello",
+		},
+		{
+			name:            "numeric id is kept as written",
+			item:            `{"id": 1234567890123, "title": "a", "content_text": "hello"}`,
+			wantTitle:       "a",
+			wantGUID:        "1234567890123",
+			wantDescription: "hello",
+		},
+		{
+			name:            "invalid id is dropped",
+			item:            `{"id": {"x": 1}, "title": "a", "content_text": "hello"}`,
+			wantTitle:       "a",
+			wantDescription: "hello",
+		},
+		{
+			name:            "content_html is not unescaped again",
+			item:            `{"id": "1", "title": "Tom &amp; Jerry", "content_html": "<p>use &lt;b&gt; for bold</p>"}`,
+			wantTitle:       "Tom &amp; Jerry",
+			wantGUID:        "1",
+			wantDescription: "<p>use &lt;b&gt; for bold</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `{"version": "https://jsonfeed.org/version/1.1", "title": "T", "items": [` + tt.item + `]}`
+			feed, err := parseFeed([]byte(data), "application/feed+json")
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+			}
+			item := feed.Channel.Items[0]
+			if item.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", item.Title, tt.wantTitle)
+			}
+			if item.GUID != tt.wantGUID {
+				t.Errorf("guid = %q, want %q", item.GUID, tt.wantGUID)
+			}
+			if item.Description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", item.Description, tt.wantDescription)
+			}
+		})
+	}
+}
+
+func TestParseJSONContentTypeIsSniffed(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantTitle string
+		wantErr   bool
+	}{
+		{
+			name:      "xml labelled as json",
+			data:      `<rss version="2.0"><channel><title>rss</title></channel></rss>`,
+			wantTitle: "rss",
+		},
+		{
+			name:    "html error page labelled as json",
+			data:    `<html><body>502 Bad Gateway</body></html>`,
+			wantErr: true,
+		},
+		{
+			name:      "json feed",
+			data:      ` {"version": "https://jsonfeed.org/version/1", "title": "json"}`,
+			wantTitle: "json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), "application/json")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFeed() = %q, want an error", feed.Channel.Title)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+			if feed.Channel.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.wantTitle)
+			}
+		})
+	}
+}
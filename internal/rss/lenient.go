@@ -0,0 +1,222 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"unicode/utf8"
+)
+
+// newLenientDecoder returns a decoder that accepts what real-world feeds get
+// wrong: unescaped ampersands, HTML entities such as &nbsp; that XML does not
+// define, and other non-fatal syntax errors.
+func newLenientDecoder(data []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
+
+// sanitizeXML drops characters XML 1.0 forbids, such as control codes
+// pasted into titles, which encoding/xml rejects even in non-strict mode.
+// data must be valid UTF-8.
+func sanitizeXML(data []byte) []byte {
+	clean := true
+	for _, r := range string(data) {
+		if !isXMLChar(r) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	for _, r := range string(data) {
+		if isXMLChar(r) {
+			out = utf8.AppendRune(out, r)
+		}
+	}
+	return out
+}
+
+func isXMLChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r < 0x20:
+		return false
+	case r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+		return false
+	}
+	return true
+}
+
+// decodeFeed decodes a feed document with the lenient decoder. If the
+// document is too broken even for that, or decodes with fewer items than
+// it has itemLocal start tags (a stray end tag can close the channel
+// early), it is decoded again without its itemLocal elements and each of
+// those is decoded on its own, so one bad item does not drop the rest.
+// items points decodeFeed at the document's item list; skipped counts the
+// items that had to be dropped.
+func decodeFeed[D, T any](data []byte, itemLocal string, items func(*D) *[]T) (doc *D, skipped int, err error) {
+	data = sanitizeXML(data)
+
+	doc = new(D)
+	err = newLenientDecoder(data).Decode(doc)
+	if err == nil && len(*items(doc)) >= countStartTags(data, itemLocal) {
+		return doc, 0, nil
+	}
+
+	rest, salvaged, skipped := salvageItems[T](data, itemLocal)
+	if err == nil && len(salvaged) <= len(*items(doc)) {
+		// The start tags were miscounted, most likely because of markup
+		// inside CDATA; the document decoded fine.
+		return doc, 0, nil
+	}
+	if len(salvaged) == 0 {
+		return nil, 0, err
+	}
+
+	salvagedDoc := new(D)
+	if restErr := decodeTruncated(rest, salvagedDoc); restErr != nil {
+		if err == nil {
+			return doc, 0, nil
+		}
+		return nil, 0, err
+	}
+	*items(salvagedDoc) = salvaged
+
+	return salvagedDoc, skipped, nil
+}
+
+// decodeTruncated decodes data leniently, accepting a document that ends
+// before its elements are closed, as it does once salvageItems has dropped
+// an unterminated last item.
+func decodeTruncated(data []byte, v any) error {
+	err := newLenientDecoder(data).Decode(v)
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Msg == "unexpected EOF" {
+		return nil
+	}
+	return err
+}
+
+// salvageItems cuts every <local>...</local> element out of data and
+// decodes each one separately. It returns the document without those
+// elements, the items that decoded, and how many did not. An unterminated
+// element, as found at the end of a truncated download, is counted as
+// skipped and cuts the document short.
+func salvageItems[T any](data []byte, local string) (rest []byte, items []T, skipped int) {
+	root := rootStartTag(data)
+	closeTag := []byte("</" + local)
+
+	rest = make([]byte, 0, len(data))
+	for {
+		start := indexStartTag(data, local)
+		if start < 0 {
+			break
+		}
+		end := bytes.Index(data[start:], closeTag)
+		gt := -1
+		if end >= 0 {
+			end += start + len(closeTag)
+			gt = bytes.IndexByte(data[end:], '>')
+		}
+		if gt < 0 {
+			skipped++
+			return append(rest, data[:start]...), items, skipped
+		}
+		end += gt + 1
+
+		var item T
+		if decodeElement(root, data[start:end], &item) == nil {
+			items = append(items, item)
+		} else {
+			skipped++
+		}
+
+		rest = append(rest, data[:start]...)
+		data = data[end:]
+	}
+
+	return append(rest, data...), items, skipped
+}
+
+// indexStartTag finds the first <local ...> start tag in data, ignoring
+// longer names that merely share the prefix.
+func indexStartTag(data []byte, local string) int {
+	open := []byte("<" + local)
+	offset := 0
+	for {
+		i := bytes.Index(data[offset:], open)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		next := i + len(open)
+		if next < len(data) {
+			switch data[next] {
+			case '>', '/', ' ', '\t', '\n', '\r':
+				return i
+			}
+		}
+		offset = next
+	}
+}
+
+// countStartTags counts the <local ...> start tags in data.
+func countStartTags(data []byte, local string) int {
+	n := 0
+	for {
+		i := indexStartTag(data, local)
+		if i < 0 {
+			return n
+		}
+		n++
+		data = data[i+len(local)+1:]
+	}
+}
+
+// rootStartTag returns the raw start tag of the document element, so items
+// decoded on their own still see the namespaces it declares.
+func rootStartTag(data []byte) []byte {
+	dec := newLenientDecoder(data)
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			return data[offset:dec.InputOffset()]
+		}
+	}
+}
+
+// decodeElement decodes the first element of chunk into v, with root
+// wrapped around it for namespace context.
+func decodeElement(root, chunk []byte, v any) error {
+	doc := make([]byte, 0, len(root)+len(chunk))
+	doc = append(doc, root...)
+	doc = append(doc, chunk...)
+
+	dec := newLenientDecoder(doc)
+	skipRoot := len(root) > 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if skipRoot {
+			skipRoot = false
+			continue
+		}
+		return dec.DecodeElement(v, &start)
+	}
+}
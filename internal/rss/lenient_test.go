@@ -0,0 +1,117 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMalformedFeeds(t *testing.T) {
+	const (
+		rssOpen  = `<rss version="2.0"><channel><title>T</title>`
+		rssClose = `</channel></rss>`
+		atomOpen = `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title>`
+		rdfOpen  = `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>T</title></channel>`
+	)
+
+	tests := []struct {
+		name        string
+		data        string
+		wantTitles  []string
+		wantSkipped int
+		wantErr     bool
+	}{
+		{
+			name:       "well-formed",
+			data:       rssOpen + `<item><title>a</title></item><item><title>b</title></item>` + rssClose,
+			wantTitles: []string{"a", "b"},
+		},
+		{
+			name:       "unescaped ampersand and html entity",
+			data:       rssOpen + `<item><title>Tom & Jerry&nbsp;!</title></item>` + rssClose,
+			wantTitles: []string{"Tom & Jerry\u00a0!"},
+		},
+		{
+			name:       "control characters are dropped",
+			data:       rssOpen + "<item><title>a\x01b\x1f</title></item>" + rssClose,
+			wantTitles: []string{"ab"},
+		},
+		{
+			name:        "broken item is skipped",
+			data:        rssOpen + `<item><title>a</title></item><item><title>b<</title></item><item><title>c</title></item>` + rssClose,
+			wantTitles:  []string{"a", "c"},
+			wantSkipped: 1,
+		},
+		{
+			name:       "mismatched end tag does not drop the following items",
+			data:       rssOpen + `<item><title>a</title></item><item><title>b</title><description>bad</foo></description></item><item><title>c</title></item><item><title>d</title></item>` + rssClose,
+			wantTitles: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:        "truncated inside the last item",
+			data:        rssOpen + `<item><title>a</title></item><item><title>b</title></item><item><title>c</ti`,
+			wantTitles:  []string{"a", "b"},
+			wantSkipped: 1,
+		},
+		{
+			name:       "truncated after an item",
+			data:       rssOpen + `<item><title>a</title></item><item><title>b</title></item>`,
+			wantTitles: []string{"a", "b"},
+		},
+		{
+			name:       "item tag inside cdata",
+			data:       rssOpen + `<item><title>a</title><description><![CDATA[<item>not an item]]></description></item>` + rssClose,
+			wantTitles: []string{"a"},
+		},
+		{
+			name:        "atom truncated inside the last entry",
+			data:        atomOpen + `<entry><title>a</title></entry><entry><title>b`,
+			wantTitles:  []string{"a"},
+			wantSkipped: 1,
+		},
+		{
+			name:       "atom mismatched end tag",
+			data:       atomOpen + `<entry><title>a</title></entry><entry><title>b</bar></title></entry><entry><title>c</title></entry></feed>`,
+			wantTitles: []string{"a", "b", "c"},
+		},
+		{
+			name:        "rdf broken item is skipped",
+			data:        rdfOpen + `<item><title>a</title></item><item><title>b<</title></item><item><title>c</title></item></rdf:RDF>`,
+			wantTitles:  []string{"a", "c"},
+			wantSkipped: 1,
+		},
+		{
+			name:    "nothing to salvage",
+			data:    rssOpen + `<item><title>a<</title></item>` + rssClose,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), "")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFeed() succeeded with %d items, want an error", len(feed.Channel.Items))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+
+			if feed.Channel.Title != "T" {
+				t.Errorf("channel title = %q, want %q", feed.Channel.Title, "T")
+			}
+			var titles []string
+			for _, it := range feed.Channel.Items {
+				titles = append(titles, it.Title)
+			}
+			if !slices.Equal(titles, tt.wantTitles) {
+				t.Errorf("item titles = %q, want %q", titles, tt.wantTitles)
+			}
+			if feed.SkippedItems != tt.wantSkipped {
+				t.Errorf("SkippedItems = %d, want %d", feed.SkippedItems, tt.wantSkipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimiter spaces out requests to the same host with a token bucket and
+// a minimum delay between consecutive requests. A host can also be blocked
+// outright, e.g. after it answers 429 Too Many Requests.
+type HostLimiter struct {
+	rate     float64
+	burst    float64
+	minDelay time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostBucket
+}
+
+type hostBucket struct {
+	tokens       float64
+	refilledAt   time.Time
+	nextAllowed  time.Time
+	blockedUntil time.Time
+}
+
+// NewHostLimiter allows rate requests per second per host with bursts of up
+// to burst requests, and at least minDelay between any two requests to the
+// same host. A rate of zero or less disables the token bucket.
+func NewHostLimiter(rate float64, burst int, minDelay time.Duration) *HostLimiter {
+	return &HostLimiter{
+		rate:     rate,
+		burst:    float64(max(burst, 1)),
+		minDelay: minDelay,
+		hosts:    make(map[string]*hostBucket),
+	}
+}
+
+// maxBlockedWait is the longest Wait will sit out a Block before giving up
+// with a HostBlockedError, so a worker is not tied up for the whole
+// Retry-After period of one host.
+const maxBlockedWait = time.Minute
+
+// HostBlockedError is returned by Wait when the host asked us to back off
+// for longer than we are willing to wait.
+type HostBlockedError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostBlockedError) Error() string {
+	return fmt.Sprintf("host %s is rate limited until %s", e.Host, e.Until.Format(time.RFC1123))
+}
+
+// Wait blocks until a request to host may be sent, or ctx is done.
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	now := time.Now()
+	if until := l.blockedUntil(host); until.Sub(now) > maxBlockedWait {
+		return &HostBlockedError{Host: host, Until: until}
+	}
+
+	delay := l.reserve(host, now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *HostLimiter) blockedUntil(host string) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.hosts[host]; ok {
+		return b.blockedUntil
+	}
+	return time.Time{}
+}
+
+// reserve claims the next slot for host and returns how long the caller
+// has to wait for it.
+func (l *HostLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &hostBucket{tokens: l.burst, refilledAt: now}
+		l.hosts[host] = b
+	}
+
+	at := now
+	if b.blockedUntil.After(at) {
+		at = b.blockedUntil
+	}
+	if b.nextAllowed.After(at) {
+		at = b.nextAllowed
+	}
+
+	if l.rate > 0 {
+		b.tokens = min(l.burst, b.tokens+now.Sub(b.refilledAt).Seconds()*l.rate)
+		b.refilledAt = now
+		b.tokens--
+		if b.tokens < 0 {
+			// The bucket may go negative: each waiter owns a later slot.
+			tokenAt := now.Add(time.Duration(-b.tokens / l.rate * float64(time.Second)))
+			if tokenAt.After(at) {
+				at = tokenAt
+			}
+		}
+	}
+
+	b.nextAllowed = at.Add(l.minDelay)
+	return at.Sub(now)
+}
+
+// Block holds back all requests to host until the given time.
+func (l *HostLimiter) Block(host string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &hostBucket{tokens: l.burst, refilledAt: time.Now()}
+		l.hosts[host] = b
+	}
+	if until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// defaultRetryAfter is assumed when a 429 response carries no usable
+// Retry-After header.
+const defaultRetryAfter = time.Minute
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	raw := strings.TrimSpace(h.Get("Retry-After"))
+	if raw == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(raw); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(raw); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+	type request struct {
+		at   time.Duration
+		want time.Duration
+	}
+
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		minDelay time.Duration
+		block    time.Duration
+		requests []request
+	}{
+		{
+			name:     "burst then one token per second",
+			rate:     1,
+			burst:    2,
+			requests: []request{{0, 0}, {0, 0}, {0, time.Second}, {0, 2 * time.Second}},
+		},
+		{
+			name:     "bucket refills over time",
+			rate:     1,
+			burst:    2,
+			requests: []request{{0, 0}, {0, 0}, {3 * time.Second, 0}, {3 * time.Second, 0}},
+		},
+		{
+			name:     "minimum delay spaces requests",
+			minDelay: 250 * time.Millisecond,
+			requests: []request{{0, 0}, {0, 250 * time.Millisecond}, {0, 500 * time.Millisecond}, {time.Second, 0}},
+		},
+		{
+			name:     "minimum delay applies on top of the bucket",
+			rate:     1,
+			burst:    1,
+			minDelay: 2 * time.Second,
+			requests: []request{{0, 0}, {0, 2 * time.Second}},
+		},
+		{
+			name:     "block holds requests back",
+			block:    10 * time.Second,
+			requests: []request{{0, 10 * time.Second}, {4 * time.Second, 6 * time.Second}, {20 * time.Second, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewHostLimiter(tt.rate, tt.burst, tt.minDelay)
+			start := time.Now()
+			if tt.block > 0 {
+				l.Block("example.com", start.Add(tt.block))
+			}
+
+			for i, r := range tt.requests {
+				if got := l.reserve("example.com", start.Add(r.at)); got != r.want {
+					t.Errorf("request %d at %s: delay = %s, want %s", i, r.at, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHostLimiterHostsAreIndependent(t *testing.T) {
+	l := NewHostLimiter(0, 1, time.Minute)
+	now := time.Now()
+
+	if got := l.reserve("a.example.com", now); got != 0 {
+		t.Errorf("first host delay = %s, want 0", got)
+	}
+	if got := l.reserve("b.example.com", now); got != 0 {
+		t.Errorf("second host delay = %s, want 0", got)
+	}
+}
+
+func TestHostLimiterWait(t *testing.T) {
+	t.Run("long block fails fast", func(t *testing.T) {
+		l := NewHostLimiter(0, 1, 0)
+		until := time.Now().Add(2 * maxBlockedWait)
+		l.Block("example.com", until)
+
+		err := l.Wait(context.Background(), "example.com")
+		var blocked *HostBlockedError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("Wait() = %v, want a HostBlockedError", err)
+		}
+		if blocked.Host != "example.com" || !blocked.Until.Equal(until) {
+			t.Errorf("HostBlockedError = %+v, want host example.com until %s", blocked, until)
+		}
+	})
+
+	t.Run("short block is waited out", func(t *testing.T) {
+		l := NewHostLimiter(0, 1, 0)
+		l.Block("example.com", time.Now().Add(maxBlockedWait/2))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := l.Wait(ctx, "example.com"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait() = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("free slot does not wait", func(t *testing.T) {
+		l := NewHostLimiter(1, 1, 0)
+		if err := l.Wait(context.Background(), "example.com"); err != nil {
+			t.Errorf("Wait() = %v, want nil", err)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "missing", want: 0},
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "zero seconds", value: "0", want: 0},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{name: "date in the past", value: now.Add(-time.Hour).Format(http.TimeFormat), want: 0},
+		{name: "negative seconds", value: "-5", want: 0},
+		{name: "garbage", value: "later", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.value != "" {
+				h.Set("Retry-After", tt.value)
+			}
+			if got := parseRetryAfter(h, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
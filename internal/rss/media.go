@@ -0,0 +1,106 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MediaRSS holds the Media RSS elements of an item, along with
+// itunes:image, which podcasts use for episode artwork.
+type MediaRSS struct {
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Groups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+
+	// MediaTitle, MediaDescription and MediaCategories are only parsed so
+	// that they do not overwrite the item's own title, description and
+	// categories.
+	MediaTitle       string   `xml:"http://search.yahoo.com/mrss/ title"`
+	MediaDescription string   `xml:"http://search.yahoo.com/mrss/ description"`
+	MediaCategories  []string `xml:"http://search.yahoo.com/mrss/ category"`
+
+	ItunesImage ItunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+type MediaThumbnail struct {
+	URL   string `xml:"url,attr"`
+	Width string `xml:"width,attr"`
+}
+
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	Width      string           `xml:"width,attr"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// MediaGroup bundles alternative renditions of the same media.
+type MediaGroup struct {
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// imageURL picks a preview image: the widest media:thumbnail anywhere in
+// the item, then the widest image media:content, then itunes:image.
+func (m MediaRSS) imageURL() string {
+	thumbnails := m.Thumbnails
+	contents := m.Contents
+	for _, g := range m.Groups {
+		thumbnails = append(thumbnails, g.Thumbnails...)
+		contents = append(contents, g.Contents...)
+	}
+	for _, c := range contents {
+		thumbnails = append(thumbnails, c.Thumbnails...)
+	}
+
+	best, bestWidth := "", -1
+	for _, t := range thumbnails {
+		if url := strings.TrimSpace(t.URL); url != "" && mediaWidth(t.Width) > bestWidth {
+			best, bestWidth = url, mediaWidth(t.Width)
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	for _, c := range contents {
+		if !isImage(c.Medium, c.Type) {
+			continue
+		}
+		if url := strings.TrimSpace(c.URL); url != "" && mediaWidth(c.Width) > bestWidth {
+			best, bestWidth = url, mediaWidth(c.Width)
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	return strings.TrimSpace(m.ItunesImage.Href)
+}
+
+func isImage(medium, mimeType string) bool {
+	return medium == "image" || strings.HasPrefix(strings.TrimSpace(mimeType), "image/")
+}
+
+// mediaWidth parses a width attribute, treating a missing one as 0 so a
+// sized rendition wins over an unsized one.
+func mediaWidth(raw string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// ImageURL returns the channel image, from <image> or itunes:image.
+func (f *RRSFeed) ImageURL() string {
+	if url := strings.TrimSpace(f.Channel.Image.URL); url != "" {
+		return url
+	}
+	return strings.TrimSpace(f.Channel.ItunesImage.Href)
+}
@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMediaRSSItem(t *testing.T) {
+	tests := []struct {
+		name            string
+		item            string
+		wantLink        string
+		wantDescription string
+		wantCategories  []string
+		wantImage       string
+	}{
+		{
+			name: "media elements do not replace the item's own",
+			item: `<item><title>a</title><link>https://example.com/a</link><description>own</description>` +
+				`<category>own</category>` +
+				`<media:title>media title</media:title><media:description>media text</media:description>` +
+				`<media:category>media</media:category></item>`,
+			wantLink:        "https://example.com/a",
+			wantDescription: "own",
+			wantCategories:  []string{"own"},
+		},
+		{
+			name: "atom self link does not blank the link",
+			item: `<item><title>a</title><link>https://example.com/a</link>` +
+				`<atom:link rel="self" href="https://example.com/a.xml"/></item>`,
+			wantLink:       "https://example.com/a",
+			wantCategories: []string{},
+		},
+		{
+			name:           "alternate atom link stands in for a missing link",
+			item:           `<item><title>a</title><atom:link rel="alternate" href="https://example.com/b"/></item>`,
+			wantLink:       "https://example.com/b",
+			wantCategories: []string{},
+		},
+		{
+			name:           "media thumbnail is the image",
+			item:           `<item><title>a</title><link>https://example.com/a</link><media:thumbnail url="https://example.com/a.jpg"/></item>`,
+			wantLink:       "https://example.com/a",
+			wantCategories: []string{},
+			wantImage:      "https://example.com/a.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:atom="http://www.w3.org/2005/Atom">` +
+				`<channel><title>T</title>` + tt.item + `</channel></rss>`
+			feed, err := parseFeed([]byte(data), "")
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+
+			item := feed.Channel.Items[0]
+			if item.Title != "a" {
+				t.Errorf("title = %q, want %q", item.Title, "a")
+			}
+			if item.Link != tt.wantLink {
+				t.Errorf("link = %q, want %q", item.Link, tt.wantLink)
+			}
+			if item.Description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", item.Description, tt.wantDescription)
+			}
+			if !slices.Equal(item.Categories, tt.wantCategories) {
+				t.Errorf("categories = %q, want %q", item.Categories, tt.wantCategories)
+			}
+			if item.Image != tt.wantImage {
+				t.Errorf("image = %q, want %q", item.Image, tt.wantImage)
+			}
+		})
+	}
+}
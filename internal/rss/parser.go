@@ -0,0 +1,156 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Parser turns a raw feed payload into the normalized RRSFeed.
+type Parser interface {
+	// Detect reports whether the parser understands the payload, given the
+	// response Content-Type and the body.
+	Detect(contentType string, data []byte) bool
+	Parse(data []byte) (*RRSFeed, error)
+}
+
+type namedParser struct {
+	name   string
+	parser Parser
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   []namedParser
+)
+
+func init() {
+	Register("rss", rssParser{})
+	Register("rdf", rdfParser{})
+	Register("atom", atomParser{})
+	Register("jsonfeed", jsonFeedParser{})
+}
+
+// Register adds a parser under name, replacing any parser already using
+// that name. Parsers registered later are consulted first, so a custom
+// parser can claim payloads a built-in one would also accept.
+func Register(name string, p Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	filtered := []namedParser{{name: name, parser: p}}
+	for _, np := range parsers {
+		if np.name != name {
+			filtered = append(filtered, np)
+		}
+	}
+	parsers = filtered
+}
+
+// Unregister removes the parser registered under name, if any.
+func Unregister(name string) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	filtered := parsers[:0]
+	for _, np := range parsers {
+		if np.name != name {
+			filtered = append(filtered, np)
+		}
+	}
+	parsers = filtered
+}
+
+func parseFeed(data []byte, contentType string) (*RRSFeed, error) {
+	parsersMu.RLock()
+	candidates := make([]namedParser, len(parsers))
+	copy(candidates, parsers)
+	parsersMu.RUnlock()
+
+	for _, np := range candidates {
+		if np.parser.Detect(contentType, data) {
+			return np.parser.Parse(data)
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized feed format (content type %q)", contentType)
+}
+
+// rootElement returns the name of the document element, or the zero
+// name if the payload is not well-formed enough to find one.
+func rootElement(data []byte) xml.Name {
+	dec := newLenientDecoder(data)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
+type rssParser struct{}
+
+func (rssParser) Detect(contentType string, data []byte) bool {
+	return rootElement(data).Local == "rss"
+}
+
+func (rssParser) Parse(data []byte) (*RRSFeed, error) {
+	feed, skipped, err := decodeFeed(data, "item", func(f *RRSFeed) *[]RSSItem {
+		return &f.Channel.Items
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the XML: %v", err)
+	}
+	feed.SkippedItems = skipped
+
+	ch := &feed.Channel
+	ch.Link = strings.TrimSpace(ch.Link)
+	if ch.Link == "" {
+		ch.Link = alternateLink(ch.AtomLinks)
+	}
+	ch.Language = strings.TrimSpace(ch.Language)
+	ch.Generator = strings.TrimSpace(ch.Generator)
+
+	for i := range feed.Channel.Items {
+		feed.Channel.Items[i].normalize()
+	}
+	feed.unescapeHTML()
+
+	return feed, nil
+}
+
+type atomParser struct{}
+
+func (atomParser) Detect(contentType string, data []byte) bool {
+	root := rootElement(data)
+	return root.Space == atomNamespace && root.Local == "feed"
+}
+
+func (atomParser) Parse(data []byte) (*RRSFeed, error) {
+	return parseAtom(data)
+}
+
+type rdfParser struct{}
+
+func (rdfParser) Detect(contentType string, data []byte) bool {
+	root := rootElement(data)
+	return root.Space == rdfNamespace && root.Local == "RDF"
+}
+
+func (rdfParser) Parse(data []byte) (*RRSFeed, error) {
+	return parseRDF(data)
+}
+
+type jsonFeedParser struct{}
+
+func (jsonFeedParser) Detect(contentType string, data []byte) bool {
+	return isJSONFeed(data)
+}
+
+func (jsonFeedParser) Parse(data []byte) (*RRSFeed, error) {
+	return parseJSONFeed(data)
+}
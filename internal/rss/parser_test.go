@@ -0,0 +1,154 @@
+package rss
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// fakeParser claims every payload that starts with prefix and returns a
+// feed titled after itself.
+type fakeParser struct {
+	title  string
+	prefix string
+}
+
+func (p fakeParser) Detect(contentType string, data []byte) bool {
+	return bytes.HasPrefix(data, []byte(p.prefix))
+}
+
+func (p fakeParser) Parse(data []byte) (*RRSFeed, error) {
+	feed := &RRSFeed{}
+	feed.Channel.Title = p.title
+	return feed, nil
+}
+
+// restoreParsers puts the registry back as it was when the test ends.
+func restoreParsers(t *testing.T) {
+	parsersMu.Lock()
+	saved := slices.Clone(parsers)
+	parsersMu.Unlock()
+
+	t.Cleanup(func() {
+		parsersMu.Lock()
+		parsers = saved
+		parsersMu.Unlock()
+	})
+}
+
+const (
+	rssDoc  = `<rss version="2.0"><channel><title>rss</title></channel></rss>`
+	rdfDoc  = `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>rdf</title></channel></rdf:RDF>`
+	atomDoc = `<feed xmlns="http://www.w3.org/2005/Atom"><title>atom</title></feed>`
+	jsonDoc = `{"version": "https://jsonfeed.org/version/1.1", "title": "json"}`
+)
+
+func TestParseFeedDetection(t *testing.T) {
+	type registration struct {
+		name   string
+		parser Parser
+	}
+
+	tests := []struct {
+		name        string
+		register    []registration
+		unregister  []string
+		contentType string
+		data        string
+		want        string
+		wantErr     bool
+	}{
+		{name: "rss", data: rssDoc, want: "rss"},
+		{name: "rdf", data: rdfDoc, want: "rdf"},
+		{name: "atom", data: atomDoc, want: "atom"},
+		{name: "json feed", data: jsonDoc, want: "json"},
+		{
+			name:        "json content type does not make xml json",
+			contentType: "application/json",
+			data:        rssDoc,
+			want:        "rss",
+		},
+		{
+			name:        "unrecognized payload",
+			contentType: "text/html",
+			data:        "<html><body>not a feed</body></html>",
+			wantErr:     true,
+		},
+		{
+			name:     "custom parser is consulted before the built-ins",
+			register: []registration{{"custom", fakeParser{title: "custom", prefix: "<rss"}}},
+			data:     rssDoc,
+			want:     "custom",
+		},
+		{
+			name:     "custom parser only claims what it detects",
+			register: []registration{{"custom", fakeParser{title: "custom", prefix: "<rss"}}},
+			data:     atomDoc,
+			want:     "atom",
+		},
+		{
+			name: "later registration wins",
+			register: []registration{
+				{"first", fakeParser{title: "first", prefix: "<rss"}},
+				{"second", fakeParser{title: "second", prefix: "<rss"}},
+			},
+			data: rssDoc,
+			want: "second",
+		},
+		{
+			name: "registering a name again replaces it and moves it first",
+			register: []registration{
+				{"first", fakeParser{title: "first", prefix: "<rss"}},
+				{"second", fakeParser{title: "second", prefix: "<rss"}},
+				{"first", fakeParser{title: "first again", prefix: "<rss"}},
+			},
+			data: rssDoc,
+			want: "first again",
+		},
+		{
+			name:       "unregistered parser is no longer consulted",
+			register:   []registration{{"custom", fakeParser{title: "custom", prefix: "<rss"}}},
+			unregister: []string{"custom"},
+			data:       rssDoc,
+			want:       "rss",
+		},
+		{
+			name:       "built-in parsers can be unregistered",
+			unregister: []string{"rss"},
+			data:       rssDoc,
+			wantErr:    true,
+		},
+		{
+			name:       "unregistering an unknown name is a no-op",
+			unregister: []string{"missing"},
+			data:       atomDoc,
+			want:       "atom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreParsers(t)
+			for _, r := range tt.register {
+				Register(r.name, r.parser)
+			}
+			for _, name := range tt.unregister {
+				Unregister(name)
+			}
+
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFeed() = %q, want an error", feed.Channel.Title)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+			if feed.Channel.Title != tt.want {
+				t.Errorf("parseFeed() title = %q, want %q", feed.Channel.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enclosure is a media file attached to an item, typically a podcast
+// episode.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// Size returns the declared length in bytes, or 0 when it is missing or
+// malformed.
+func (e Enclosure) Size() int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// PlayTime returns the itunes:duration of the item, which may be written
+// as seconds, MM:SS or HH:MM:SS. It returns 0 when the item has none.
+func (it *RSSItem) PlayTime() time.Duration {
+	raw := strings.TrimSpace(it.Duration)
+	if raw == "" {
+		return 0
+	}
+
+	var seconds int
+	for _, part := range strings.Split(raw, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// EpisodeNumber returns itunes:episode, or 0 when it is missing.
+func (it *RSSItem) EpisodeNumber() int {
+	return positiveInt(it.Episode)
+}
+
+// SeasonNumber returns itunes:season, or 0 when it is missing.
+func (it *RSSItem) SeasonNumber() int {
+	return positiveInt(it.Season)
+}
+
+func positiveInt(raw string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+)
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// rdfFeed is the RSS 1.0 layout, where items are siblings of the channel
+// under <rdf:RDF> rather than children of it.
+type rdfFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		Language        string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []rdfItem `xml:"item"`
+}
+
+type rdfItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func parseRDF(data []byte) (*RRSFeed, error) {
+	rf, skipped, err := decodeFeed(data, "item", func(f *rdfFeed) *[]rdfItem {
+		return &f.Items
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the RDF XML: %v", err)
+	}
+
+	feed := RRSFeed{SkippedItems: skipped}
+	feed.Channel.Title = rf.Channel.Title
+	feed.Channel.Link = strings.TrimSpace(rf.Channel.Link)
+	feed.Channel.Description = rf.Channel.Description
+	feed.Channel.Language = strings.TrimSpace(rf.Channel.Language)
+	feed.Channel.Image.URL = strings.TrimSpace(rf.Image.URL)
+	feed.Channel.UpdatePeriod = rf.Channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = rf.Channel.UpdateFrequency
+
+	for _, it := range rf.Items {
+		item := RSSItem{
+			Title:       it.Title,
+			Link:        strings.TrimSpace(it.Link),
+			Description: it.Description,
+			PubDate:     strings.TrimSpace(it.Date),
+			GUID:        it.About,
+			Categories:  it.Subjects,
+			Content:     it.Content,
+			Creator:     it.Creator,
+		}
+		item.normalize()
+
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	feed.unescapeHTML()
+
+	return &feed, nil
+}
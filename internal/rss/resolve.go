@@ -0,0 +1,97 @@
+package rss
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// embeddedURL matches href and src attributes in item HTML, with the value
+// in the second group when double-quoted and the third when single-quoted.
+var embeddedURL = regexp.MustCompile(`(?i)(\s(?:href|src)\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+
+// resolveURLs makes the links in the feed absolute. Channel-level URLs are
+// resolved against the channel's xml:base, itself relative to feedURL.
+// Item URLs are resolved against the item's xml:base if it has one, or
+// else the channel's xml:base, the channel link or feedURL, in that order.
+func (f *RRSFeed) resolveURLs(feedURL string) {
+	ch := &f.Channel
+
+	channelBase, err := url.Parse(feedURL)
+	if err != nil {
+		return
+	}
+	if ch.Base != "" {
+		channelBase = resolveBase(channelBase, ch.Base)
+	}
+
+	ch.Link = resolveURL(channelBase, ch.Link)
+	ch.Image.URL = resolveURL(channelBase, ch.Image.URL)
+	ch.ItunesImage.Href = resolveURL(channelBase, ch.ItunesImage.Href)
+	ch.Icon = resolveURL(channelBase, ch.Icon)
+
+	// The site link is the natural base for item links when the channel
+	// declares no xml:base: a feed served from a CDN or feed proxy still
+	// links to pages on the site.
+	itemBase := channelBase
+	if ch.Base == "" {
+		if site, err := url.Parse(ch.Link); err == nil && site.IsAbs() {
+			itemBase = site
+		}
+	}
+
+	for i := range ch.Items {
+		it := &ch.Items[i]
+
+		base := itemBase
+		if it.Base != "" {
+			base = resolveBase(channelBase, it.Base)
+		}
+
+		it.Link = resolveURL(base, it.Link)
+		it.Image = resolveURL(base, it.Image)
+		for j := range it.Enclosures {
+			it.Enclosures[j].URL = resolveURL(base, it.Enclosures[j].URL)
+		}
+		it.Description = resolveEmbeddedURLs(base, it.Description)
+		it.Content = resolveEmbeddedURLs(base, it.Content)
+	}
+}
+
+// resolveBase applies an xml:base attribute to base, keeping base when the
+// attribute is malformed.
+func resolveBase(base *url.URL, attr string) *url.URL {
+	ref, err := url.Parse(strings.TrimSpace(attr))
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(ref)
+}
+
+// resolveURL returns raw made absolute against base. Empty, absolute,
+// fragment-only and malformed references are returned unchanged.
+func resolveURL(base *url.URL, raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "#") {
+		return raw
+	}
+
+	ref, err := url.Parse(raw)
+	if err != nil || ref.Scheme != "" {
+		return raw
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func resolveEmbeddedURLs(base *url.URL, html string) string {
+	if html == "" {
+		return html
+	}
+	return embeddedURL.ReplaceAllStringFunc(html, func(attr string) string {
+		m := embeddedURL.FindStringSubmatch(attr)
+		if strings.HasSuffix(attr, "'") {
+			return m[1] + "'" + resolveURL(base, m[3]) + "'"
+		}
+		return m[1] + `"` + resolveURL(base, m[2]) + `"`
+	})
+}
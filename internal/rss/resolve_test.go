@@ -0,0 +1,141 @@
+package rss
+
+import "testing"
+
+func TestResolveURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		feedURL     string
+		channelBase string
+		channelLink string
+		itemBase    string
+		link        string
+		want        string
+	}{
+		{
+			name:    "absolute link is kept",
+			feedURL: "https://feeds.example.com/blog.xml",
+			link:    "https://other.example.org/post",
+			want:    "https://other.example.org/post",
+		},
+		{
+			name:    "relative to the feed url",
+			feedURL: "https://example.com/blog/feed.xml",
+			link:    "posts/1",
+			want:    "https://example.com/blog/posts/1",
+		},
+		{
+			name:        "channel link beats the feed url",
+			feedURL:     "https://cdn.example.net/feeds/blog.xml",
+			channelLink: "https://example.com/blog/",
+			link:        "/posts/1",
+			want:        "https://example.com/posts/1",
+		},
+		{
+			name:        "relative channel link is resolved first",
+			feedURL:     "https://example.com/feeds/blog.xml",
+			channelLink: "/blog/",
+			link:        "posts/1",
+			want:        "https://example.com/blog/posts/1",
+		},
+		{
+			name:        "channel xml:base beats the channel link",
+			feedURL:     "https://example.com/feed.xml",
+			channelBase: "https://static.example.com/archive/",
+			channelLink: "https://example.com/",
+			link:        "2024/post",
+			want:        "https://static.example.com/archive/2024/post",
+		},
+		{
+			name:        "item xml:base is relative to the channel base",
+			feedURL:     "https://example.com/feed.xml",
+			channelBase: "/archive/",
+			itemBase:    "2024/",
+			link:        "post",
+			want:        "https://example.com/archive/2024/post",
+		},
+		{
+			name:    "scheme-relative link",
+			feedURL: "https://example.com/feed.xml",
+			link:    "//cdn.example.com/post",
+			want:    "https://cdn.example.com/post",
+		},
+		{
+			name:    "fragment is kept as is",
+			feedURL: "https://example.com/feed.xml",
+			link:    "#comments",
+			want:    "#comments",
+		},
+		{
+			name:    "empty link stays empty",
+			feedURL: "https://example.com/feed.xml",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &RRSFeed{}
+			feed.Channel.Base = tt.channelBase
+			feed.Channel.Link = tt.channelLink
+			feed.Channel.Items = []RSSItem{{Link: tt.link, Base: tt.itemBase}}
+
+			feed.resolveURLs(tt.feedURL)
+
+			if got := feed.Channel.Items[0].Link; got != tt.want {
+				t.Errorf("item link = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEmbeddedURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "double-quoted href",
+			html: `<a href="/about">about</a>`,
+			want: `<a href="https://example.com/about">about</a>`,
+		},
+		{
+			name: "single-quoted src",
+			html: `<img src='img/a.png'>`,
+			want: `<img src='https://example.com/posts/img/a.png'>`,
+		},
+		{
+			name: "absolute and fragment urls are kept",
+			html: `<a href="https://other.org/x">x</a> <a href="#top">top</a>`,
+			want: `<a href="https://other.org/x">x</a> <a href="#top">top</a>`,
+		},
+		{
+			name: "attribute names are case-insensitive",
+			html: `<IMG SRC="a.png">`,
+			want: `<IMG SRC="https://example.com/posts/a.png">`,
+		},
+		{
+			name: "text mentioning href is left alone",
+			html: `<p>set href=/x in the tag</p>`,
+			want: `<p>set href=/x in the tag</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &RRSFeed{}
+			feed.Channel.Items = []RSSItem{{Description: tt.html, Content: tt.html}}
+
+			feed.resolveURLs("https://example.com/posts/feed.xml")
+
+			item := feed.Channel.Items[0]
+			if item.Description != tt.want {
+				t.Errorf("description = %q, want %q", item.Description, tt.want)
+			}
+			if item.Content != tt.want {
+				t.Errorf("content = %q, want %q", item.Content, tt.want)
+			}
+		})
+	}
+}
@@ -2,67 +2,210 @@ package rss
 
 import (
 	"context"
-	"encoding/xml"
 	"fmt"
 	"html"
-	"io"
-	"net/http"
+	"strings"
+	"time"
 )
 
 type RRSFeed struct {
 	Channel struct {
+		// Namespaced elements must come before the plain ones they share a
+		// name with; see RSSItem. Many RSS feeds carry an
+		// <atom:link rel="self"> next to <link>.
+		ItunesImage ItunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		AtomLinks   []atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		Generator   string    `xml:"generator"`
 		Items       []RSSItem `xml:"item"`
+
+		// Icon is a small square logo. Only Atom and JSON Feed declare
+		// one.
+		Icon string `xml:"-"`
+
+		// Base is the xml:base in effect for the channel.
+		Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+
+		// Publisher refresh hints: RSS 2.0 <ttl> in minutes and the
+		// syndication module's sy:updatePeriod / sy:updateFrequency.
+		TTL             string `xml:"ttl"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+
+		// Quiet windows in GMT during which the publisher asks not to be
+		// polled.
+		SkipHours []string `xml:"skipHours>hour"`
+		SkipDays  []string `xml:"skipDays>day"`
 	} `xml:"channel"`
+
+	// SkippedItems counts items dropped because they were too malformed
+	// to parse.
+	SkippedItems int `xml:"-"`
 }
 
 type RSSItem struct {
+	// Namespaced elements are listed first: encoding/xml hands an element
+	// to the first field with a matching name, and a field without a
+	// namespace matches every namespace, so <itunes:author> would
+	// otherwise end up in Author.
+	MediaRSS
+
+	// Podcast details from the iTunes namespace. ItunesTitle, ItunesAuthor
+	// and ItunesSummary are only fallbacks for Title, Author and
+	// Description.
+	Duration      string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode       string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Season        string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	EpisodeType   string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType"`
+	ItunesTitle   string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	ItunesAuthor  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	ItunesSummary string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+
+	// Content is the full body from content:encoded, when the feed
+	// carries more than the description.
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+
+	// Creator is dc:creator, which many feeds use instead of <author>.
+	// Parsers fold it into Author.
+	Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+
+	// AtomLinks keeps <atom:link rel="self"> and friends out of Link. An
+	// alternate one stands in for a missing <link>.
+	AtomLinks []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+
+	GUID       string   `xml:"guid"`
+	Author     string   `xml:"author"`
+	Categories []string `xml:"category"`
+
+	Enclosures []Enclosure `xml:"enclosure"`
+
+	// Image is the preview image picked from the media elements or an
+	// image enclosure.
+	Image string `xml:"-"`
+
+	// Base is the item's own xml:base, if any.
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RRSFeed, error) {
+// normalize trims the item fields, fills Title, Author and Description
+// from their dc: and itunes: alternatives, and picks the preview image.
+func (it *RSSItem) normalize() {
+	if strings.TrimSpace(it.Title) == "" {
+		it.Title = strings.TrimSpace(it.ItunesTitle)
+	}
+	it.GUID = strings.TrimSpace(it.GUID)
+	it.Author = strings.TrimSpace(it.Author)
+	if it.Author == "" {
+		it.Author = strings.TrimSpace(it.Creator)
+	}
+	if it.Author == "" {
+		it.Author = strings.TrimSpace(it.ItunesAuthor)
+	}
+	if strings.TrimSpace(it.Description) == "" {
+		it.Description = strings.TrimSpace(it.ItunesSummary)
+	}
+	it.Content = strings.TrimSpace(it.Content)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating the request: %v", err)
+	enclosures := it.Enclosures[:0]
+	for _, e := range it.Enclosures {
+		if e.URL = strings.TrimSpace(e.URL); e.URL != "" {
+			e.Type = strings.TrimSpace(e.Type)
+			enclosures = append(enclosures, e)
+		}
 	}
+	it.Enclosures = enclosures
 
-	req.Header.Set("User-Agent", "gator")
+	if strings.TrimSpace(it.Link) == "" {
+		it.Link = alternateLink(it.AtomLinks)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error doing the request: %v", err)
+	// Podcast episodes often have no <link>; the media file is the best
+	// stable URL they offer.
+	if strings.TrimSpace(it.Link) == "" && len(it.Enclosures) > 0 {
+		it.Link = it.Enclosures[0].URL
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected server response: %v", err)
+	if it.Image == "" {
+		it.Image = it.MediaRSS.imageURL()
+	}
+	for _, e := range it.Enclosures {
+		if it.Image == "" && isImage("", e.Type) {
+			it.Image = e.URL
+		}
 	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading the response: %v", err)
+	categories := make([]string, 0, len(it.Categories))
+	for _, c := range it.Categories {
+		if c = strings.TrimSpace(c); c != "" {
+			categories = append(categories, c)
+		}
 	}
+	it.Categories = categories
+}
 
-	var feed RRSFeed
+// Validators are the cache validators from a previous response, sent
+// back to the server so it can answer 304 Not Modified.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
 
-	if err := xml.Unmarshal(data, &feed); err != nil {
-		return nil, fmt.Errorf("error parsing the XML: %v", err)
-	}
+type FetchResult struct {
+	Feed        *RRSFeed
+	StatusCode  int
+	NotModified bool
+	Validators  Validators
 
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	// FinalURL is the URL the feed was served from after any redirects.
+	// PermanentRedirect is set when every redirect on the way there was a
+	// 301 or 308, meaning the feed has moved for good.
+	FinalURL          string
+	Redirected        bool
+	PermanentRedirect bool
+}
 
-	for i := range feed.Channel.Items {
-		feed.Channel.Items[i].Title = html.UnescapeString(feed.Channel.Items[i].Title)
-		feed.Channel.Items[i].Description = html.UnescapeString(feed.Channel.Items[i].Description)
-	}
+// StatusError is returned when the server answers with a status other
+// than 200 or 304.
+type StatusError struct {
+	StatusCode int
+	Status     string
 
-	return &feed, nil
+	// RetryAfter is how long the server asked us to wait, for 429 and 503
+	// responses.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected server response: %s", e.Status)
+}
+
+// FetchFeed fetches a feed with a Fetcher using the default options.
+func FetchFeed(ctx context.Context, feedURL string, cached Validators) (*FetchResult, error) {
+	return defaultFetcher.Fetch(ctx, feedURL, cached)
+}
+
+// unescapeHTML decodes the entities left in titles, descriptions and
+// content by the many RSS feeds that escape their HTML twice. Atom and JSON
+// Feed say whether a field holds HTML, so their parsers leave it as is.
+func (f *RRSFeed) unescapeHTML() {
+	f.Channel.Title = html.UnescapeString(f.Channel.Title)
+	f.Channel.Description = html.UnescapeString(f.Channel.Description)
+
+	for i := range f.Channel.Items {
+		f.Channel.Items[i].Title = html.UnescapeString(f.Channel.Items[i].Title)
+		f.Channel.Items[i].Description = html.UnescapeString(f.Channel.Items[i].Description)
+		f.Channel.Items[i].Content = html.UnescapeString(f.Channel.Items[i].Content)
+	}
 }
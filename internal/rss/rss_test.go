@@ -0,0 +1,73 @@
+package rss
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDoubleEscapedRSS(t *testing.T) {
+	data := `<rss version="2.0"><channel><title>Tom &amp;amp; Jerry</title>` +
+		`<item><title>a &amp;amp; b</title><description>&amp;lt;p&amp;gt;hi&amp;lt;/p&amp;gt;</description></item>` +
+		`</channel></rss>`
+
+	feed, err := parseFeed([]byte(data), "")
+	if err != nil {
+		t.Fatalf("parseFeed() error: %v", err)
+	}
+
+	if got, want := feed.Channel.Title, "Tom & Jerry"; got != want {
+		t.Errorf("channel title = %q, want %q", got, want)
+	}
+	item := feed.Channel.Items[0]
+	if got, want := item.Title, "a & b"; got != want {
+		t.Errorf("item title = %q, want %q", got, want)
+	}
+	if got, want := item.Description, "<p>hi</p>"; got != want {
+		t.Errorf("item description = %q, want %q", got, want)
+	}
+}
+
+func TestParseItemDetails(t *testing.T) {
+	tests := []struct {
+		name           string
+		item           string
+		wantContent    string
+		wantCategories []string
+	}{
+		{
+			name:           "no categories is an empty list",
+			item:           `<item><title>a</title></item>`,
+			wantCategories: []string{},
+		},
+		{
+			name:           "blank categories are dropped",
+			item:           `<item><title>a</title><category> go </category><category> </category></item>`,
+			wantCategories: []string{"go"},
+		},
+		{
+			name:           "content is unescaped like the description",
+			item:           `<item><title>a</title><content:encoded>&amp;lt;p&amp;gt;full&amp;lt;/p&amp;gt;</content:encoded></item>`,
+			wantContent:    "<p>full</p>",
+			wantCategories: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>T</title>` +
+				tt.item + `</channel></rss>`
+			feed, err := parseFeed([]byte(data), "")
+			if err != nil {
+				t.Fatalf("parseFeed() error: %v", err)
+			}
+
+			item := feed.Channel.Items[0]
+			if item.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", item.Content, tt.wantContent)
+			}
+			if item.Categories == nil || !slices.Equal(item.Categories, tt.wantCategories) {
+				t.Errorf("categories = %#v, want %#v", item.Categories, tt.wantCategories)
+			}
+		})
+	}
+}
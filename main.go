@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/angelchiav/blog-aggregator-go/internal/commands"
 	"github.com/angelchiav/blog-aggregator-go/internal/config"
 	"github.com/angelchiav/blog-aggregator-go/internal/database"
+	"github.com/angelchiav/blog-aggregator-go/internal/rss"
 )
 
 func main() {
@@ -30,6 +36,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Feed fetcher
+
+	var proxy *url.URL
+	if cfg.Fetch.Proxy != "" {
+		proxy, err = url.Parse(cfg.Fetch.Proxy)
+		if err != nil {
+			log.Fatalf("invalid fetch proxy %q: %v", cfg.Fetch.Proxy, err)
+		}
+	}
+
+	rate, burst, minDelay := cfg.Fetch.HostLimits()
+	fetcher := rss.NewFetcher(rss.Options{
+		ConnectTimeout: time.Duration(cfg.Fetch.ConnectTimeout),
+		ReadTimeout:    time.Duration(cfg.Fetch.ReadTimeout),
+		Timeout:        time.Duration(cfg.Fetch.Timeout),
+		MaxBodySize:    cfg.Fetch.MaxBodyBytes,
+		MaxRedirects:   cfg.Fetch.MaxRedirects,
+		Proxy:          proxy,
+		UserAgent:      cfg.Fetch.UserAgent,
+		Limiter:        rss.NewHostLimiter(rate, burst, minDelay),
+	})
+
 	// Starting PostgreSQL DB
 
 	db, err := sql.Open("postgres", cfg.DBURL)
@@ -42,8 +70,10 @@ func main() {
 	// State Instance
 
 	state := &commands.State{
-		Cfg: &cfg,
-		DB:  database.New(db),
+		Cfg:     &cfg,
+		DB:      database.New(db),
+		Conn:    db,
+		Fetcher: fetcher,
 	}
 
 	cmdName := os.Args[1]
@@ -59,19 +89,29 @@ func main() {
 
 	// Command registry
 	reg := commands.Commands{}
-	reg.Register("login", (*commands.State).HandlerLogin)
-	reg.Register("register", (*commands.State).HandlerRegister)
-	reg.Register("reset", (*commands.State).HandlerReset)
-	reg.Register("users", (*commands.State).HandlerUsers)
-	reg.Register("agg", (*commands.State).HandlerAgg)
+	reg.Register("login", commands.Method((*commands.State).HandlerLogin))
+	reg.Register("register", commands.Method((*commands.State).HandlerRegister))
+	reg.Register("reset", commands.Method((*commands.State).HandlerReset))
+	reg.Register("users", commands.Method((*commands.State).HandlerUsers))
+	reg.Register("agg", commands.Method((*commands.State).HandlerAgg))
+	reg.Register("fetch", commands.Method((*commands.State).HandlerFetch))
 	reg.Register("addfeed", commands.MiddlewareLoggedIn(commands.HandlerAddFeed))
-	reg.Register("feeds", (*commands.State).HandlerGetFeed)
+	reg.Register("feeds", commands.Method((*commands.State).HandlerGetFeed))
+	reg.Register("broken-feeds", commands.Method((*commands.State).HandlerBrokenFeeds))
+	reg.Register("enable-feed", commands.Method((*commands.State).HandlerEnableFeed))
+	reg.Register("feed-log", commands.Method((*commands.State).HandlerFeedLog))
+	reg.Register("feed-info", commands.Method((*commands.State).HandlerFeedInfo))
 	reg.Register("follow", commands.MiddlewareLoggedIn(commands.HandlerFeedFollow))
 	reg.Register("following", commands.MiddlewareLoggedIn(commands.HandlerFeedFollowing))
 	reg.Register("unfollow", commands.MiddlewareLoggedIn(commands.HandlerFeedUnfollow))
 	reg.Register("browse", commands.MiddlewareLoggedIn(commands.HandlerBrowse))
+	reg.Register("post-history", commands.Method((*commands.State).HandlerPostHistory))
+
+	// Cancelled on Ctrl-C or a service stop so handlers can wind down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := reg.Run(state, cmd); err != nil {
+	if err := reg.Run(ctx, state, cmd); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,22 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (
+    id,
+    post_id,
+    created_at,
+    url,
+    mime_type,
+    length_bytes,
+    duration_seconds,
+    episode,
+    season
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: GetEnclosuresForPosts :many
+SELECT *
+FROM enclosures
+WHERE post_id = ANY(@post_ids::uuid[])
+ORDER BY created_at, url;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (
+    id,
+    feed_id,
+    started_at,
+    duration_ms,
+    status_code,
+    new_posts,
+    error
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: GetFeedFetches :many
+SELECT *
+FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY started_at DESC
+LIMIT $2;
+
+-- name: MoveFeedFetches :exec
+UPDATE feed_fetches
+SET feed_id = @to_feed_id
+WHERE feed_id = @from_feed_id;
\ No newline at end of file
@@ -34,7 +34,7 @@ JOIN users u ON u.id = i.user_id
 JOIN feeds f ON f.id = i.feed_id;
 
 -- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id
+SELECT *
 FROM feeds
 WHERE url = $1;
 
@@ -46,7 +46,8 @@ SELECT
     ff.user_id,
     ff.feed_id,
     u.name AS user_name,
-    f.name AS feed_name
+    f.name AS feed_name,
+    f.status AS feed_status
 FROM feed_follows AS ff
 JOIN users AS u ON u.id = ff.user_id
 JOIN feeds AS f ON f.id = ff.feed_id
@@ -58,14 +59,132 @@ DELETE FROM feed_follows
 WHERE user_id = $1
   AND feed_id = $2;
 
--- name: MarkFeedFetched :exec
+-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1;
+
+-- name: UpdateFeedValidators :exec
+UPDATE feeds
+SET etag          = $2,
+    last_modified = $3,
+    updated_at    = NOW()
+WHERE id = $1;
+
+-- name: ClaimFeed :one
+-- Leases one feed for a manual fetch. Returns no row while another
+-- aggregator holds the lease.
+UPDATE feeds
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NOW() + make_interval(secs => @lease_seconds::float8),
+    updated_at       = NOW()
+WHERE id = @id
+  AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+RETURNING *;
+
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at  = NOW(),
+    lease_expires_at = NOW() + make_interval(secs => @lease_seconds::float8),
+    updated_at       = NOW()
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+      AND (last_fetched_at IS NULL OR last_fetched_at < @fetched_before::timestamptz)
+      AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+      AND status = 'active'
+      AND (rate_limited_until IS NULL OR rate_limited_until <= NOW())
+      -- Back off exponentially after failures: 1m, 2m, 4m, ... capped at a day.
+      -- The exponent is clamped too, or the interval overflows after ~40
+      -- failures and the whole claim query errors out.
+      AND (
+        consecutive_failures = 0
+        OR last_error_at + LEAST(INTERVAL '1 minute' * POWER(2, LEAST(consecutive_failures - 1, 11)), INTERVAL '1 day') <= NOW()
+      )
+    ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST, created_at ASC
+    LIMIT @max_feeds
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordFeedFailure :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
-    updated_at      = NOW()
+SET last_error           = @last_error,
+    last_error_at        = NOW(),
+    consecutive_failures = consecutive_failures + 1,
+    failing_since        = COALESCE(failing_since, NOW()),
+    status               = CASE
+        WHEN status = 'dead' OR @gone::bool THEN 'dead'
+        WHEN @dead_after_seconds::float8 > 0
+             AND failing_since < NOW() - make_interval(secs => @dead_after_seconds::float8) THEN 'dead'
+        WHEN @max_failures::int > 0 AND consecutive_failures + 1 >= @max_failures::int THEN 'paused'
+        ELSE status
+    END,
+    updated_at           = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    failing_since        = NULL,
+    updated_at           = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: GetBrokenFeeds :many
 SELECT *
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, created_at ASC
-LIMIT 1;
\ No newline at end of file
+WHERE status <> 'active'
+   OR consecutive_failures > 0
+ORDER BY status = 'active', consecutive_failures DESC, last_error_at DESC;
+
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET status               = 'active',
+    consecutive_failures = 0,
+    failing_since        = NULL,
+    updated_at           = NOW()
+WHERE url = $1;
+
+-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET next_fetch_at             = $2,
+    declared_interval_seconds = $3,
+    skip_hours                = $4,
+    skip_days                 = $5
+WHERE id = $1;
+
+-- name: SetFeedRateLimited :exec
+UPDATE feeds
+SET rate_limited_until = $2,
+    updated_at         = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url        = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), ff.created_at, NOW(), ff.user_id, @to_feed_id::uuid
+FROM feed_follows AS ff
+WHERE ff.feed_id = @from_feed_id
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_url    = $2,
+    description = $3,
+    language    = $4,
+    generator   = $5,
+    icon_url    = $6,
+    image_url   = $7,
+    updated_at  = NOW()
+WHERE id = $1;
\ No newline at end of file
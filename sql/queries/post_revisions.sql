@@ -0,0 +1,20 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (
+    id,
+    post_id,
+    updated_at,
+    replaced_at,
+    title,
+    description,
+    content,
+    published_at
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: GetPostRevisions :many
+SELECT *
+FROM post_revisions
+WHERE post_id = $1
+ORDER BY replaced_at;
\ No newline at end of file
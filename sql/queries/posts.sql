@@ -1,4 +1,4 @@
--- name: CreatePost :exec
+-- name: UpsertPost :one
 INSERT INTO posts (
     id,
     created_at,
@@ -7,16 +7,65 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    guid,
+    author,
+    categories,
+    content,
+    image_url
 )
 VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
-);
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+ON CONFLICT (url) DO UPDATE
+SET title        = EXCLUDED.title,
+    description  = EXCLUDED.description,
+    published_at = COALESCE(EXCLUDED.published_at, posts.published_at),
+    guid         = EXCLUDED.guid,
+    author       = EXCLUDED.author,
+    categories   = EXCLUDED.categories,
+    content      = EXCLUDED.content,
+    image_url    = EXCLUDED.image_url,
+    updated_at   = EXCLUDED.updated_at
+-- Only posts of the same feed are updated, and only when something
+-- changed; otherwise no row is returned.
+WHERE posts.feed_id = EXCLUDED.feed_id
+  AND (posts.title, posts.description, posts.published_at, posts.guid,
+       posts.author, posts.categories, posts.content, posts.image_url)
+      IS DISTINCT FROM
+      (EXCLUDED.title, EXCLUDED.description, COALESCE(EXCLUDED.published_at, posts.published_at), EXCLUDED.guid,
+       EXCLUDED.author, EXCLUDED.categories, EXCLUDED.content, EXCLUDED.image_url)
+RETURNING id, (xmax = 0) AS inserted;
+
+-- name: GetPostByURL :one
+SELECT *
+FROM posts
+WHERE url = $1;
 
 -- name: GetPostsForUser :many
-SELECT p.*
+SELECT p.*, f.name AS feed_name, f.image_url AS feed_image_url
 FROM posts p
 JOIN feed_follows ff ON ff.feed_id = p.feed_id
-WHERE ff.user_id = $1
+JOIN feeds f ON f.id = p.feed_id
+WHERE ff.user_id = @user_id
+  AND (sqlc.narg('category')::text IS NULL
+       OR EXISTS (
+           SELECT 1
+           FROM unnest(p.categories) AS c
+           WHERE lower(c) = lower(sqlc.narg('category'))
+       ))
 ORDER BY p.published_at DESC NULLS LAST, p.created_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT @max_posts;
+
+-- name: GetRecentPublishTimes :many
+SELECT published_at
+FROM posts
+WHERE feed_id = $1
+  AND published_at IS NOT NULL
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = @to_feed_id
+WHERE feed_id = @from_feed_id;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN lease_expires_at TIMESTAMPTZ NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN lease_expires_at;
\ No newline at end of file
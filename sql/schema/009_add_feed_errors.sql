@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN last_error TEXT NULL,
+ADD COLUMN last_error_at TIMESTAMPTZ NULL,
+ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN disabled_at TIMESTAMPTZ NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_error,
+DROP COLUMN last_error_at,
+DROP COLUMN consecutive_failures,
+DROP COLUMN disabled_at;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    status_code INTEGER,
+    new_posts INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_feed_id_started_at_idx ON feed_fetches (feed_id, started_at DESC);
+
+-- +goose Down
+DROP TABLE feed_fetches;
\ No newline at end of file
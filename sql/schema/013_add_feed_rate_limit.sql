@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN rate_limited_until TIMESTAMPTZ NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN rate_limited_until;
\ No newline at end of file
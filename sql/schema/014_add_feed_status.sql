@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'paused', 'dead')),
+ADD COLUMN failing_since TIMESTAMPTZ NULL;
+
+UPDATE feeds SET status = 'paused' WHERE disabled_at IS NOT NULL;
+UPDATE feeds SET failing_since = last_error_at WHERE consecutive_failures > 0;
+
+ALTER TABLE feeds
+DROP COLUMN disabled_at;
+
+-- +goose Down
+ALTER TABLE feeds
+ADD COLUMN disabled_at TIMESTAMPTZ NULL;
+
+UPDATE feeds SET disabled_at = updated_at WHERE status <> 'active';
+
+ALTER TABLE feeds
+DROP COLUMN status,
+DROP COLUMN failing_since;
\ No newline at end of file
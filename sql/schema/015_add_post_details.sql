@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT,
+ADD COLUMN author TEXT,
+ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}',
+ADD COLUMN content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN guid,
+DROP COLUMN author,
+DROP COLUMN categories,
+DROP COLUMN content;
\ No newline at end of file
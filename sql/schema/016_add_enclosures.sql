@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE enclosures (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length_bytes BIGINT,
+    duration_seconds INTEGER,
+    episode INTEGER,
+    season INTEGER,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
\ No newline at end of file
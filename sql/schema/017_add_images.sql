@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN image_url TEXT;
+
+ALTER TABLE posts
+ADD COLUMN image_url TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN image_url;
+
+ALTER TABLE feeds
+DROP COLUMN image_url;
\ No newline at end of file
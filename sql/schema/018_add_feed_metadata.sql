@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN site_url TEXT,
+ADD COLUMN description TEXT,
+ADD COLUMN language TEXT,
+ADD COLUMN generator TEXT,
+ADD COLUMN icon_url TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN site_url,
+DROP COLUMN description,
+DROP COLUMN language,
+DROP COLUMN generator,
+DROP COLUMN icon_url;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    updated_at TIMESTAMPTZ NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    content TEXT,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX post_revisions_post_id_replaced_at_idx ON post_revisions (post_id, replaced_at);
+
+-- +goose Down
+DROP TABLE post_revisions;
\ No newline at end of file